@@ -1,14 +1,15 @@
 package app
 
 import (
-	sdk "github.com/cosmos/cosmos-sdk/types"
-)
+	"fmt"
 
-const (
-	AccountAddressPrefix = "bitsong"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
+// The bech32 prefixes below are those of BitSong until SetConfig sets them
+// from the active chain profile.
 var (
+	AccountAddressPrefix   = "bitsong"
 	AccountPubKeyPrefix    = AccountAddressPrefix + "pub"
 	ValidatorAddressPrefix = AccountAddressPrefix + "valoper"
 	ValidatorPubKeyPrefix  = AccountAddressPrefix + "valoperpub"
@@ -16,15 +17,24 @@ var (
 	ConsNodePubKeyPrefix   = AccountAddressPrefix + "valconspub"
 )
 
+// SetConfig sets the bech32 prefixes and the coin type of the active chain
+// profile and seals the SDK config. The prefix variables above are updated to
+// the ones of the active profile.
 func SetConfig() {
+	AccountAddressPrefix = ActiveProfile.AccountPrefix
+	AccountPubKeyPrefix = AccountAddressPrefix + sdk.PrefixPublic
+	ValidatorAddressPrefix = AccountAddressPrefix + sdk.PrefixValidator + sdk.PrefixOperator
+	ValidatorPubKeyPrefix = AccountAddressPrefix + sdk.PrefixValidator + sdk.PrefixOperator + sdk.PrefixPublic
+	ConsNodeAddressPrefix = AccountAddressPrefix + sdk.PrefixValidator + sdk.PrefixConsensus
+	ConsNodePubKeyPrefix = AccountAddressPrefix + sdk.PrefixValidator + sdk.PrefixConsensus + sdk.PrefixPublic
+
 	config := sdk.GetConfig()
 	config.SetBech32PrefixForAccount(AccountAddressPrefix, AccountPubKeyPrefix)
 	config.SetBech32PrefixForValidator(ValidatorAddressPrefix, ValidatorPubKeyPrefix)
 	config.SetBech32PrefixForConsensusNode(ConsNodeAddressPrefix, ConsNodePubKeyPrefix)
 
-	// 639 is the registered coin type for BTSG
-	// Following the coin type registered at https://github.com/satoshilabs/slips/blob/master/slip-0044.md
-	config.SetCoinType(639)
-	config.SetFullFundraiserPath("44'/639'/0'/0/0")
+	// coin types are registered at https://github.com/satoshilabs/slips/blob/master/slip-0044.md
+	config.SetCoinType(ActiveProfile.CoinType)
+	config.SetFullFundraiserPath(fmt.Sprintf("44'/%d'/0'/0/0", ActiveProfile.CoinType))
 	config.Seal()
 }
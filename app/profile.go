@@ -0,0 +1,77 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// ChainProfile holds the chain specific settings of a network handled by
+// genutils.
+type ChainProfile struct {
+	Name          string `json:"name"`
+	AccountPrefix string `json:"account_prefix"`
+	CoinType      uint32 `json:"coin_type"`
+	BondDenom     string `json:"bond_denom"`
+	// NodeHome is the default home directory, relative to the user home
+	// directory unless absolute.
+	NodeHome string `json:"node_home"`
+	ChainID  string `json:"chain_id"`
+}
+
+// BitSongProfile is the profile of the BitSong mainnet. 639 is the registered
+// coin type for BTSG.
+var BitSongProfile = ChainProfile{
+	Name:          "bitsong",
+	AccountPrefix: AccountAddressPrefix,
+	CoinType:      639,
+	BondDenom:     "ubtsg",
+	NodeHome:      ".bitsongd",
+	ChainID:       "bitsong-2b",
+}
+
+// BuiltinProfiles are the profiles available without a configuration file.
+var BuiltinProfiles = []ChainProfile{BitSongProfile}
+
+// ActiveProfile is the profile selected for the current invocation.
+var ActiveProfile = BitSongProfile
+
+// SetChainProfile makes p the active profile and derives DefaultNodeHome from
+// it. It must be called before SetConfig.
+func SetChainProfile(p ChainProfile) {
+	ActiveProfile = p
+	DefaultNodeHome = p.homeDir()
+}
+
+// Validate performs a stateless check of the profile.
+func (p ChainProfile) Validate() error {
+	if p.Name == "" {
+		return errors.New("chain profile must have a name")
+	}
+	if p.AccountPrefix == "" || strings.ToLower(p.AccountPrefix) != p.AccountPrefix {
+		return fmt.Errorf("chain profile %s: invalid account prefix %q", p.Name, p.AccountPrefix)
+	}
+	if err := sdk.ValidateDenom(p.BondDenom); err != nil {
+		return fmt.Errorf("chain profile %s: invalid bond denom: %w", p.Name, err)
+	}
+	if p.NodeHome == "" {
+		return fmt.Errorf("chain profile %s: missing node home", p.Name)
+	}
+	return nil
+}
+
+func (p ChainProfile) homeDir() string {
+	if filepath.IsAbs(p.NodeHome) {
+		return p.NodeHome
+	}
+
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return p.NodeHome
+	}
+	return filepath.Join(userHomeDir, p.NodeHome)
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	weightingLinear = "linear"
+	weightingSqrt   = "sqrt"
+	weightingLog    = "log"
+)
+
+// AirdropPolicy describes how an airdrop budget is split between the accounts
+// of a staked snapshot.
+type AirdropPolicy struct {
+	Denom             string   `json:"denom"`
+	TotalAmount       sdk.Int  `json:"total_amount"`
+	MinStake          sdk.Int  `json:"min_stake"`
+	MinUsdValue       sdk.Int  `json:"min_usd_value"`
+	WhaleCap          sdk.Int  `json:"whale_cap"`
+	Weighting         string   `json:"weighting"`
+	ExcludedAddresses []string `json:"excluded_addresses"`
+}
+
+// AirdropAllocations is the result of compute-airdrop.
+type AirdropAllocations struct {
+	Denom          string       `json:"denom,omitempty"`
+	Total          sdk.Int      `json:"total"`
+	NumberAccounts uint64       `json:"num_accounts"`
+	Allocations    []Allocation `json:"allocations"`
+}
+
+// Allocation is the airdrop amount of a single address.
+type Allocation struct {
+	Address string  `json:"address"`
+	Amount  sdk.Int `json:"amount"`
+}
+
+// ComputeAirdropCmd returns compute-airdrop cobra Command.
+func ComputeAirdropCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compute-airdrop [snapshot-file] [policy-file] [output-file]",
+		Short: "Compute airdrop allocations from a staked snapshot",
+		Long: `Compute airdrop allocations from a snapshot written by export-staked-snapshot.
+
+The YAML or JSON policy sets the total_amount to distribute and optionally the
+min_stake and min_usd_value an account needs to be eligible, a whale_cap on the
+stake taken into account, the weighting applied to the stake (linear, sqrt or
+log) and a list of excluded_addresses.
+
+Each eligible account receives its share of the budget rounded down; the
+remaining units are then handed out one by one to the accounts with the
+largest rounding remainders, ties broken by address, so the allocations
+always sum up to total_amount.
+
+Example:
+	genutils compute-airdrop snapshot.json policy.yaml allocations.json
+`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var snapshot DeriveSnapshotStaked
+			bz, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read snapshot: %w", err)
+			}
+			if err := unmarshalYAMLOrJSON(args[0], bz, &snapshot); err != nil {
+				return fmt.Errorf("failed to parse snapshot %s: %w", args[0], err)
+			}
+
+			policy, err := loadAirdropPolicy(args[1])
+			if err != nil {
+				return err
+			}
+
+			allocations, err := computeAirdrop(snapshot, policy)
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("allocated %s%s to %d accounts\n", allocations.Total, policy.Denom, allocations.NumberAccounts)
+			return writeJSONFile(args[2], allocations)
+		},
+	}
+
+	return cmd
+}
+
+// loadAirdropPolicy reads and validates an airdrop policy.
+func loadAirdropPolicy(path string) (AirdropPolicy, error) {
+	var policy AirdropPolicy
+
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read policy: %w", err)
+	}
+	if err := unmarshalYAMLOrJSON(path, bz, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+
+	if policy.Weighting == "" {
+		policy.Weighting = weightingLinear
+	}
+	if policy.MinStake.IsNil() {
+		policy.MinStake = sdk.ZeroInt()
+	}
+	if policy.MinUsdValue.IsNil() {
+		policy.MinUsdValue = sdk.ZeroInt()
+	}
+
+	return policy, policy.Validate()
+}
+
+// Validate performs a stateless check of the policy.
+func (p AirdropPolicy) Validate() error {
+	if p.TotalAmount.IsNil() || !p.TotalAmount.IsPositive() {
+		return errors.New("total_amount must be positive")
+	}
+	if p.Denom != "" {
+		if err := sdk.ValidateDenom(p.Denom); err != nil {
+			return fmt.Errorf("invalid denom: %w", err)
+		}
+	}
+	if p.MinStake.IsNegative() {
+		return errors.New("min_stake cannot be negative")
+	}
+	if p.MinUsdValue.IsNegative() {
+		return errors.New("min_usd_value cannot be negative")
+	}
+	if !p.WhaleCap.IsNil() && !p.WhaleCap.IsPositive() {
+		return errors.New("whale_cap must be positive")
+	}
+
+	switch p.Weighting {
+	case weightingLinear, weightingSqrt, weightingLog:
+	default:
+		return fmt.Errorf("unknown weighting %q, expected %s, %s or %s", p.Weighting, weightingLinear, weightingSqrt, weightingLog)
+	}
+
+	for _, addr := range p.ExcludedAddresses {
+		if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+			return fmt.Errorf("invalid excluded address %s: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// weight returns the weight of an account with the given stake.
+func (p AirdropPolicy) weight(stake sdk.Int) (sdk.Dec, error) {
+	if !p.WhaleCap.IsNil() && stake.GT(p.WhaleCap) {
+		stake = p.WhaleCap
+	}
+
+	switch p.Weighting {
+	case weightingSqrt:
+		return stake.ToDec().ApproxSqrt()
+	case weightingLog:
+		// rounded so that the weight does not depend on floating point noise
+		w := math.Log1p(float64(stake.Int64()))
+		return sdk.NewDecFromStr(strconv.FormatFloat(w, 'f', 12, 64))
+	default:
+		return stake.ToDec(), nil
+	}
+}
+
+// computeAirdrop splits policy.TotalAmount between the eligible accounts of
+// snapshot proportionally to their weight.
+func computeAirdrop(snapshot DeriveSnapshotStaked, policy AirdropPolicy) (AirdropAllocations, error) {
+	excluded := make(map[string]bool, len(policy.ExcludedAddresses))
+	for _, addr := range policy.ExcludedAddresses {
+		excluded[addr] = true
+	}
+
+	type candidate struct {
+		address   string
+		weight    sdk.Dec
+		amount    sdk.Int
+		remainder sdk.Int
+	}
+
+	seen := make(map[string]bool, len(snapshot.Accounts))
+	totalWeight := sdk.ZeroDec()
+	var candidates []candidate
+	for _, acc := range snapshot.Accounts {
+		if seen[acc.Address] {
+			return AirdropAllocations{}, fmt.Errorf("duplicate snapshot account %s", acc.Address)
+		}
+		seen[acc.Address] = true
+
+		if excluded[acc.Address] || acc.Staked.IsNil() || acc.Staked.LT(policy.MinStake) {
+			continue
+		}
+		if policy.MinUsdValue.IsPositive() && (acc.UsdValue.IsNil() || acc.UsdValue.LT(policy.MinUsdValue)) {
+			continue
+		}
+
+		if policy.Weighting == weightingLog && !acc.Staked.IsInt64() {
+			return AirdropAllocations{}, fmt.Errorf("stake of %s is too large for log weighting", acc.Address)
+		}
+		w, err := policy.weight(acc.Staked)
+		if err != nil {
+			return AirdropAllocations{}, fmt.Errorf("failed to weight %s: %w", acc.Address, err)
+		}
+		if !w.IsPositive() {
+			continue
+		}
+
+		totalWeight = totalWeight.Add(w)
+		candidates = append(candidates, candidate{address: acc.Address, weight: w})
+	}
+
+	if len(candidates) == 0 {
+		return AirdropAllocations{}, errors.New("no eligible account in snapshot")
+	}
+
+	// the weights share the same decimal precision, so the shares are computed
+	// exactly on their underlying integers
+	sum := sdk.NewIntFromBigInt(totalWeight.BigInt())
+	allocated := sdk.ZeroInt()
+	for i := range candidates {
+		share := policy.TotalAmount.Mul(sdk.NewIntFromBigInt(candidates[i].weight.BigInt()))
+		candidates[i].amount = share.Quo(sum)
+		candidates[i].remainder = share.Mod(sum)
+		allocated = allocated.Add(candidates[i].amount)
+	}
+
+	// largest remainder method, ties broken by address
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].remainder.Equal(candidates[j].remainder) {
+			return candidates[i].remainder.GT(candidates[j].remainder)
+		}
+		return candidates[i].address < candidates[j].address
+	})
+	for i := 0; i < len(candidates) && allocated.LT(policy.TotalAmount); i++ {
+		candidates[i].amount = candidates[i].amount.AddRaw(1)
+		allocated = allocated.AddRaw(1)
+	}
+	if !allocated.Equal(policy.TotalAmount) {
+		return AirdropAllocations{}, fmt.Errorf("allocated %s%s instead of the total amount of %s%s",
+			allocated, policy.Denom, policy.TotalAmount, policy.Denom)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].address < candidates[j].address
+	})
+
+	result := AirdropAllocations{Denom: policy.Denom, Total: allocated}
+	for _, c := range candidates {
+		if !c.amount.IsPositive() {
+			continue
+		}
+		result.Allocations = append(result.Allocations, Allocation{Address: c.address, Amount: c.amount})
+	}
+	result.NumberAccounts = uint64(len(result.Allocations))
+
+	return result, nil
+}
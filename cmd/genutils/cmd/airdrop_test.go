@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeAirdrop(t *testing.T) {
+	snapshot := func(stakes ...int64) DeriveSnapshotStaked {
+		var s DeriveSnapshotStaked
+		for i, stake := range stakes {
+			s.Accounts = append(s.Accounts, StakedAccount{Address: fmt.Sprintf("addr%02d", i), Staked: sdk.NewInt(stake)})
+		}
+		s.NumberAccounts = uint64(len(s.Accounts))
+		return s
+	}
+	policy := func(total int64, weighting string) AirdropPolicy {
+		return AirdropPolicy{
+			Denom:       "ubtsg",
+			TotalAmount: sdk.NewInt(total),
+			MinStake:    sdk.ZeroInt(),
+			MinUsdValue: sdk.ZeroInt(),
+			Weighting:   weighting,
+		}
+	}
+	manyStakes := make([]int64, 97)
+	for i := range manyStakes {
+		manyStakes[i] = int64(i*i*7919%100003 + 1)
+	}
+
+	tests := []struct {
+		name     string
+		snapshot DeriveSnapshotStaked
+		policy   AirdropPolicy
+		// want is the amount of every address, nil to only check the total
+		want    map[string]int64
+		wantErr bool
+	}{
+		{
+			name:     "ties broken by address",
+			snapshot: snapshot(1, 1, 1),
+			policy:   policy(10, weightingLinear),
+			want:     map[string]int64{"addr00": 4, "addr01": 3, "addr02": 3},
+		},
+		{
+			name:     "largest remainder first",
+			snapshot: snapshot(1, 2),
+			policy:   policy(100, weightingLinear),
+			want:     map[string]int64{"addr00": 33, "addr01": 67},
+		},
+		{
+			name:     "several units of remainder",
+			snapshot: snapshot(1, 1, 1, 1, 1, 1, 1),
+			policy:   policy(10, weightingLinear),
+			want: map[string]int64{
+				"addr00": 2, "addr01": 2, "addr02": 2,
+				"addr03": 1, "addr04": 1, "addr05": 1, "addr06": 1,
+			},
+		},
+		{
+			name:     "budget smaller than accounts",
+			snapshot: snapshot(1, 1, 1),
+			policy:   policy(2, weightingLinear),
+			want:     map[string]int64{"addr00": 1, "addr01": 1},
+		},
+		{
+			name:     "sqrt weighting sums up to budget",
+			snapshot: snapshot(manyStakes...),
+			policy:   policy(1_000_000_007, weightingSqrt),
+		},
+		{
+			name:     "log weighting sums up to budget",
+			snapshot: snapshot(manyStakes...),
+			policy:   policy(999_999_999_999, weightingLog),
+		},
+		{
+			name:     "linear weighting sums up to budget",
+			snapshot: snapshot(manyStakes...),
+			policy:   policy(3, weightingLinear),
+		},
+		{
+			name:     "min stake and exclusions",
+			snapshot: snapshot(5, 10, 20, 30),
+			policy: func() AirdropPolicy {
+				p := policy(100, weightingLinear)
+				p.MinStake = sdk.NewInt(10)
+				p.ExcludedAddresses = []string{"addr03"}
+				return p
+			}(),
+			want: map[string]int64{"addr01": 33, "addr02": 67},
+		},
+		{
+			name:     "whale cap",
+			snapshot: snapshot(10, 1000),
+			policy: func() AirdropPolicy {
+				p := policy(100, weightingLinear)
+				p.WhaleCap = sdk.NewInt(10)
+				return p
+			}(),
+			want: map[string]int64{"addr00": 50, "addr01": 50},
+		},
+		{
+			name:     "no eligible account",
+			snapshot: snapshot(1, 2),
+			policy: func() AirdropPolicy {
+				p := policy(100, weightingLinear)
+				p.MinStake = sdk.NewInt(3)
+				return p
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "duplicate account",
+			snapshot: DeriveSnapshotStaked{Accounts: []StakedAccount{
+				{Address: "addr00", Staked: sdk.NewInt(1)},
+				{Address: "addr00", Staked: sdk.NewInt(2)},
+			}},
+			policy:  policy(100, weightingLinear),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := computeAirdrop(tc.snapshot, tc.policy)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			sum := sdk.ZeroInt()
+			for i, alloc := range result.Allocations {
+				require.True(t, alloc.Amount.IsPositive(), alloc.Address)
+				if i > 0 {
+					require.Less(t, result.Allocations[i-1].Address, alloc.Address)
+				}
+				sum = sum.Add(alloc.Amount)
+			}
+			require.Equal(t, tc.policy.TotalAmount.String(), sum.String())
+			require.Equal(t, tc.policy.TotalAmount.String(), result.Total.String())
+			require.Equal(t, uint64(len(result.Allocations)), result.NumberAccounts)
+
+			if tc.want == nil {
+				return
+			}
+			got := make(map[string]int64, len(result.Allocations))
+			for _, alloc := range result.Allocations {
+				got[alloc.Address] = alloc.Amount.Int64()
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/app"
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const flagFromPrefix = "from-prefix"
+
+// prefixModules are the modules whose addresses are converted by
+// convert-prefix.
+var prefixModules = []string{
+	authtypes.ModuleName,
+	banktypes.ModuleName,
+	stakingtypes.ModuleName,
+	distrtypes.ModuleName,
+	govtypes.ModuleName,
+	slashingtypes.ModuleName,
+	authz.ModuleName,
+	feegrant.ModuleName,
+}
+
+// bech32Suffixes are the suffixes appended to the account prefix to build the
+// human readable part of every address kind.
+var bech32Suffixes = []string{
+	"",
+	sdk.PrefixPublic,
+	sdk.PrefixValidator + sdk.PrefixOperator,
+	sdk.PrefixValidator + sdk.PrefixOperator + sdk.PrefixPublic,
+	sdk.PrefixValidator + sdk.PrefixConsensus,
+	sdk.PrefixValidator + sdk.PrefixConsensus + sdk.PrefixPublic,
+}
+
+// ConvertPrefixCmd returns convert-prefix cobra Command.
+func ConvertPrefixCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert-prefix [input-genesis-file] [new-prefix] [output-genesis-file]",
+		Short: "Re-encode every address of a genesis with a new bech32 prefix",
+		Long: `Re-encode the account, validator operator and consensus addresses of the auth,
+bank, staking, distribution, gov, slashing, authz and feegrant states with a new
+bech32 prefix. The old prefix defaults to the one of the chain profile.
+
+The number of converted addresses is reported per module, along with every
+bech32 string that is not an address of the old prefix and is therefore left
+as is.
+
+Example:
+	genutils convert-prefix bitsong_export.json sister new-sister-genesis.json
+`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := cmd.Flags().GetString(flagFromPrefix)
+			if err != nil {
+				return err
+			}
+			if from == "" {
+				from = app.ActiveProfile.AccountPrefix
+			}
+			to := args[1]
+			if to == "" || strings.ToLower(to) != to {
+				return fmt.Errorf("invalid prefix %q", to)
+			}
+
+			clientCtx := client.GetClientContextFromCmd(cmd)
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+			before := g.Clone()
+
+			report, err := convertPrefix(g.AppState, from, to)
+			if err != nil {
+				return err
+			}
+
+			for _, module := range prefixModules {
+				if n := report.Converted[module]; n > 0 {
+					cmd.Printf("%s: converted %d addresses\n", module, n)
+				}
+			}
+			for _, s := range report.Unclassified {
+				cmd.Printf("could not classify %s: %s\n", s.Path, s.Value)
+			}
+
+			if err := exportGenesisFile(cmd, g, args[2]); err != nil {
+				return err
+			}
+
+			return writeChangeReport(cmd, before, g, args[0], args[2])
+		},
+	}
+
+	cmd.Flags().String(flagFromPrefix, "", "Account prefix of the addresses to convert (default account prefix of the chain profile)")
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// PrefixReport is the outcome of convertPrefix.
+type PrefixReport struct {
+	// Converted is the number of converted addresses per module.
+	Converted map[string]int
+	// Unclassified lists the bech32 strings left unchanged.
+	Unclassified []UnclassifiedString
+}
+
+// UnclassifiedString is a bech32 string found at Path, e.g.
+// staking.validators[0].description.details, that could not be converted.
+type UnclassifiedString struct {
+	Path  string
+	Value string
+}
+
+// convertPrefix re-encodes with prefix to every address of prefix from found
+// in the prefixModules states of genState.
+func convertPrefix(genState map[string]json.RawMessage, from, to string) (PrefixReport, error) {
+	report := PrefixReport{Converted: make(map[string]int)}
+
+	hrps := make(map[string]string, len(bech32Suffixes))
+	for _, suffix := range bech32Suffixes {
+		hrps[from+suffix] = to + suffix
+	}
+
+	for _, module := range prefixModules {
+		moduleState, ok := genState[module]
+		if !ok {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(moduleState))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return report, fmt.Errorf("failed to decode %s genesis state: %w", module, err)
+		}
+
+		c := prefixConverter{hrps: hrps, report: &report, module: module}
+		v = c.convert(module, v)
+
+		bz, err := json.Marshal(v)
+		if err != nil {
+			return report, fmt.Errorf("failed to encode %s genesis state: %w", module, err)
+		}
+		genState[module] = bz
+	}
+
+	sort.Slice(report.Unclassified, func(i, j int) bool {
+		return report.Unclassified[i].Path < report.Unclassified[j].Path
+	})
+	return report, nil
+}
+
+// prefixConverter walks a decoded module state and converts its addresses.
+type prefixConverter struct {
+	hrps   map[string]string
+	report *PrefixReport
+	module string
+}
+
+func (c prefixConverter) convert(path string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = c.convert(path+"."+k, val)
+		}
+		return v
+
+	case []interface{}:
+		for i, val := range v {
+			v[i] = c.convert(fmt.Sprintf("%s[%d]", path, i), val)
+		}
+		return v
+
+	case string:
+		return c.convertString(path, v)
+
+	default:
+		return v
+	}
+}
+
+// convertString returns s re-encoded if it is an address of the old prefix.
+// Other bech32 strings, and strings using an old human readable part with an
+// invalid checksum, are reported as unclassified.
+func (c prefixConverter) convertString(path, s string) string {
+	hrp, bz, err := bech32.DecodeAndConvert(s)
+	if err != nil {
+		for old := range c.hrps {
+			if strings.HasPrefix(s, old+"1") {
+				c.unclassified(path, s)
+				break
+			}
+		}
+		return s
+	}
+
+	newHRP, ok := c.hrps[hrp]
+	if !ok {
+		c.unclassified(path, s)
+		return s
+	}
+
+	converted, err := bech32.ConvertAndEncode(newHRP, bz)
+	if err != nil {
+		c.unclassified(path, s)
+		return s
+	}
+	c.report.Converted[c.module]++
+	return converted
+}
+
+func (c prefixConverter) unclassified(path, s string) {
+	c.report.Unclassified = append(c.report.Unclassified, UnclassifiedString{Path: path, Value: s})
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertPrefix(t *testing.T) {
+	encode := func(hrp string, bz []byte) string {
+		s, err := bech32.ConvertAndEncode(hrp, bz)
+		require.NoError(t, err)
+		return s
+	}
+	accBz := []byte("account-address-0001")
+	valBz := []byte("validator-address-01")
+	consBz := []byte("consensus-address-01")
+
+	tests := []struct {
+		name             string
+		genState         map[string]string
+		want             map[string]string
+		wantConverted    map[string]int
+		wantUnclassified []UnclassifiedString
+		// wantExact are substrings expected verbatim in the module states
+		wantExact []string
+	}{
+		{
+			name: "every address kind",
+			genState: map[string]string{
+				"bank": `{"balances":[{"address":"` + encode("bitsong", accBz) + `","coins":[]}]}`,
+				"staking": `{"validators":[{"operator_address":"` + encode("bitsongvaloper", valBz) + `"}],` +
+					`"delegations":[{"delegator_address":"` + encode("bitsong", accBz) + `","validator_address":"` + encode("bitsongvaloper", valBz) + `"}]}`,
+				"slashing": `{"signing_infos":[{"address":"` + encode("bitsongvalcons", consBz) + `"}]}`,
+			},
+			want: map[string]string{
+				"bank": `{"balances":[{"address":"` + encode("sister", accBz) + `","coins":[]}]}`,
+				"staking": `{"validators":[{"operator_address":"` + encode("sistervaloper", valBz) + `"}],` +
+					`"delegations":[{"delegator_address":"` + encode("sister", accBz) + `","validator_address":"` + encode("sistervaloper", valBz) + `"}]}`,
+				"slashing": `{"signing_infos":[{"address":"` + encode("sistervalcons", consBz) + `"}]}`,
+			},
+			wantConverted: map[string]int{"bank": 1, "staking": 3, "slashing": 1},
+		},
+		{
+			name: "foreign and invalid bech32 strings are reported",
+			genState: map[string]string{
+				"auth": `{"accounts":[{"address":"` + encode("cosmos", accBz) + `"},{"address":"bitsong1invalid"}],"params":{"max_memo_characters":"256"}}`,
+			},
+			want: map[string]string{
+				"auth": `{"accounts":[{"address":"` + encode("cosmos", accBz) + `"},{"address":"bitsong1invalid"}],"params":{"max_memo_characters":"256"}}`,
+			},
+			wantConverted: map[string]int{},
+			wantUnclassified: []UnclassifiedString{
+				{Path: "auth.accounts[0].address", Value: encode("cosmos", accBz)},
+				{Path: "auth.accounts[1].address", Value: "bitsong1invalid"},
+			},
+		},
+		{
+			name: "other modules are left untouched",
+			genState: map[string]string{
+				"wasm": `{"contracts":[{"creator":"` + encode("bitsong", accBz) + `"}]}`,
+			},
+			want: map[string]string{
+				"wasm": `{"contracts":[{"creator":"` + encode("bitsong", accBz) + `"}]}`,
+			},
+			wantConverted: map[string]int{},
+		},
+		{
+			name: "numbers keep their precision",
+			genState: map[string]string{
+				"distribution": `{"fee_pool":{"community_pool":[{"denom":"ubtsg","amount":"1.5"}]},"previous_proposer":"","n":12345678901234567890}`,
+			},
+			want: map[string]string{
+				"distribution": `{"fee_pool":{"community_pool":[{"denom":"ubtsg","amount":"1.5"}]},"previous_proposer":"","n":12345678901234567890}`,
+			},
+			wantConverted: map[string]int{},
+			wantExact:     []string{`"n":12345678901234567890`, `"amount":"1.5"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			genState := make(map[string]json.RawMessage, len(tc.genState))
+			for module, state := range tc.genState {
+				genState[module] = json.RawMessage(state)
+			}
+
+			report, err := convertPrefix(genState, "bitsong", "sister")
+			require.NoError(t, err)
+
+			for module, want := range tc.want {
+				require.JSONEq(t, want, string(genState[module]), module)
+			}
+			require.Equal(t, tc.wantConverted, report.Converted)
+			require.Equal(t, tc.wantUnclassified, report.Unclassified)
+			var states []string
+			for _, state := range genState {
+				states = append(states, string(state))
+			}
+			for _, exact := range tc.wantExact {
+				require.Contains(t, strings.Join(states, "\n"), exact)
+			}
+		})
+	}
+}
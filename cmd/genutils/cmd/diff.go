@@ -0,0 +1,610 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+// GenesisDiff is the structural difference between two genesis files.
+type GenesisDiff struct {
+	Header      []FieldChange   `json:"header"`
+	Accounts    AccountsDiff    `json:"accounts"`
+	Balances    []BalanceDelta  `json:"balances"`
+	Supply      []DenomDelta    `json:"supply"`
+	Validators  ValidatorsDiff  `json:"validators"`
+	Delegations DelegationsDiff `json:"delegations"`
+	Params      []FieldChange   `json:"params"`
+	Summary     []SummaryCount  `json:"summary"`
+	Modules     ModulesDiff     `json:"modules"`
+	Unchanged   []string        `json:"unchanged_modules"`
+	changed     map[string]bool
+}
+
+// FieldChange is a field whose value differs, e.g. gov.voting_params.voting_period.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// AccountsDiff lists the addresses of the auth accounts only present in one
+// of the genesis files.
+type AccountsDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// BalanceDelta is the change of balance of an address.
+type BalanceDelta struct {
+	Address string       `json:"address"`
+	Deltas  []DenomDelta `json:"deltas"`
+}
+
+// DenomDelta is the change of amount of a denom.
+type DenomDelta struct {
+	Denom string  `json:"denom"`
+	Old   sdk.Int `json:"old"`
+	New   sdk.Int `json:"new"`
+}
+
+// Delta returns New - Old.
+func (d DenomDelta) Delta() sdk.Int {
+	return d.New.Sub(d.Old)
+}
+
+// ValidatorsDiff lists the validators added, removed or changed, by operator
+// address.
+type ValidatorsDiff struct {
+	Added   []string          `json:"added"`
+	Removed []string          `json:"removed"`
+	Changed []ValidatorChange `json:"changed"`
+}
+
+// ValidatorChange lists the changed fields of a validator present in both
+// genesis files.
+type ValidatorChange struct {
+	Operator string        `json:"operator"`
+	Fields   []FieldChange `json:"fields"`
+}
+
+// DelegationsDiff counts the delegations added, removed or whose shares
+// changed, by delegator and validator address.
+type DelegationsDiff struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+// Rewritten returns the number of delegations that differ.
+func (d DelegationsDiff) Rewritten() int {
+	return d.Added + d.Removed + d.Changed
+}
+
+// SummaryCount compares the number of entries of a collection.
+type SummaryCount struct {
+	Name string `json:"name"`
+	Old  int    `json:"old"`
+	New  int    `json:"new"`
+}
+
+// ModulesDiff lists the modules only present in one of the app states.
+type ModulesDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// DiffGenesisCmd returns diff-genesis cobra Command.
+func DiffGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-genesis [genesis-file-a] [genesis-file-b]",
+		Short: "Show the structural difference between two genesis files",
+		Long: `Compare two genesis files module by module and report what changed from a to b:
+the chain header, the auth accounts added or removed, the balance deltas per
+address and denom, the supply deltas, the validators added, removed or changed,
+the number of delegations added, removed or whose shares changed, the module
+params and the number of entries of the main collections.
+
+Example:
+	genutils diff-genesis bitsong_export.json new-bitsong-genesis.json
+	genutils diff-genesis bitsong_export.json new-bitsong-genesis.json -o json
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			a, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+			b, err := genesis.Load(clientCtx.Codec, args[1])
+			if err != nil {
+				return err
+			}
+
+			diff, err := diffGenesis(a, b)
+			if err != nil {
+				return err
+			}
+
+			output, err := cmd.Flags().GetString(flagOutput)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				bz, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(bz))
+			default:
+				diff.writeText(cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP(flagOutput, "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+// diffGenesis compares genesis a with genesis b.
+func diffGenesis(a, b *genesis.Genesis) (GenesisDiff, error) {
+	diff := GenesisDiff{changed: make(map[string]bool)}
+	docA, docB := a.Doc, b.Doc
+	genStateA, genStateB := a.AppState, b.AppState
+
+	diff.Header = diffFields(nil, "chain_id", docA.ChainID, docB.ChainID)
+	diff.Header = diffFields(diff.Header, "genesis_time", docA.GenesisTime.String(), docB.GenesisTime.String())
+	diff.Header = diffFields(diff.Header, "initial_height", fmt.Sprint(docA.InitialHeight), fmt.Sprint(docB.InitialHeight))
+	diff.Header = diffFields(diff.Header, "validators", fmt.Sprint(len(docA.Validators)), fmt.Sprint(len(docB.Validators)))
+
+	for module := range genStateA {
+		if _, ok := genStateB[module]; !ok {
+			diff.Modules.Removed = append(diff.Modules.Removed, module)
+		}
+	}
+	for module := range genStateB {
+		if _, ok := genStateA[module]; !ok {
+			diff.Modules.Added = append(diff.Modules.Added, module)
+		}
+	}
+	sort.Strings(diff.Modules.Added)
+	sort.Strings(diff.Modules.Removed)
+
+	if err := diff.diffAuth(genStateA, genStateB); err != nil {
+		return diff, err
+	}
+	if err := diff.diffBank(a, b); err != nil {
+		return diff, err
+	}
+	if err := diff.diffStaking(a, b); err != nil {
+		return diff, err
+	}
+	if err := diff.diffParams(genStateA, genStateB); err != nil {
+		return diff, err
+	}
+
+	for module, stateA := range genStateA {
+		stateB, ok := genStateB[module]
+		if ok && !diff.changed[module] && jsonEqual(stateA, stateB) {
+			diff.Unchanged = append(diff.Unchanged, module)
+		}
+	}
+	sort.Strings(diff.Unchanged)
+
+	return diff, nil
+}
+
+func (d *GenesisDiff) diffAuth(genStateA, genStateB map[string]json.RawMessage) error {
+	accountsA, err := accountAddresses(genStateA)
+	if err != nil {
+		return err
+	}
+	accountsB, err := accountAddresses(genStateB)
+	if err != nil {
+		return err
+	}
+
+	d.Accounts.Added = missingKeys(accountsB, accountsA)
+	d.Accounts.Removed = missingKeys(accountsA, accountsB)
+	d.Summary = append(d.Summary, SummaryCount{Name: "accounts", Old: len(accountsA), New: len(accountsB)})
+	d.changed[authtypes.ModuleName] = len(d.Accounts.Added) > 0 || len(d.Accounts.Removed) > 0
+	return nil
+}
+
+func (d *GenesisDiff) diffBank(a, b *genesis.Genesis) error {
+	bankA, err := a.Bank()
+	if err != nil {
+		return err
+	}
+	bankB, err := b.Bank()
+	if err != nil {
+		return err
+	}
+
+	balancesA := make(map[string]sdk.Coins, len(bankA.Balances))
+	for _, balance := range bankA.Balances {
+		balancesA[balance.Address] = balancesA[balance.Address].Add(balance.Coins...)
+	}
+	balancesB := make(map[string]sdk.Coins, len(bankB.Balances))
+	for _, balance := range bankB.Balances {
+		balancesB[balance.Address] = balancesB[balance.Address].Add(balance.Coins...)
+	}
+
+	addresses := make(map[string]bool, len(balancesA)+len(balancesB))
+	for addr := range balancesA {
+		addresses[addr] = true
+	}
+	for addr := range balancesB {
+		addresses[addr] = true
+	}
+	for _, addr := range sortedKeys(addresses) {
+		if deltas := diffCoins(balancesA[addr], balancesB[addr]); len(deltas) > 0 {
+			d.Balances = append(d.Balances, BalanceDelta{Address: addr, Deltas: deltas})
+		}
+	}
+
+	d.Supply = diffCoins(bankA.Supply, bankB.Supply)
+	d.Summary = append(d.Summary, SummaryCount{Name: "balances", Old: len(bankA.Balances), New: len(bankB.Balances)})
+	d.changed[banktypes.ModuleName] = len(d.Balances) > 0 || len(d.Supply) > 0
+	return nil
+}
+
+func (d *GenesisDiff) diffStaking(a, b *genesis.Genesis) error {
+	stakingA, err := a.Staking()
+	if err != nil {
+		return err
+	}
+	stakingB, err := b.Staking()
+	if err != nil {
+		return err
+	}
+
+	validatorsA := make(map[string]stakingtypes.Validator, len(stakingA.Validators))
+	for _, val := range stakingA.Validators {
+		validatorsA[val.OperatorAddress] = val
+	}
+	validatorsB := make(map[string]stakingtypes.Validator, len(stakingB.Validators))
+	for _, val := range stakingB.Validators {
+		validatorsB[val.OperatorAddress] = val
+	}
+
+	for _, val := range stakingB.Validators {
+		old, ok := validatorsA[val.OperatorAddress]
+		if !ok {
+			d.Validators.Added = append(d.Validators.Added, val.OperatorAddress)
+			continue
+		}
+		if fields := diffValidator(old, val); len(fields) > 0 {
+			d.Validators.Changed = append(d.Validators.Changed, ValidatorChange{Operator: val.OperatorAddress, Fields: fields})
+		}
+	}
+	for _, val := range stakingA.Validators {
+		if _, ok := validatorsB[val.OperatorAddress]; !ok {
+			d.Validators.Removed = append(d.Validators.Removed, val.OperatorAddress)
+		}
+	}
+	sort.Strings(d.Validators.Added)
+	sort.Strings(d.Validators.Removed)
+	sort.Slice(d.Validators.Changed, func(i, j int) bool {
+		return d.Validators.Changed[i].Operator < d.Validators.Changed[j].Operator
+	})
+
+	type delegationKey struct{ delegator, validator string }
+	sharesA := make(map[delegationKey]sdk.Dec, len(stakingA.Delegations))
+	for _, del := range stakingA.Delegations {
+		sharesA[delegationKey{del.DelegatorAddress, del.ValidatorAddress}] = del.Shares
+	}
+	for _, del := range stakingB.Delegations {
+		key := delegationKey{del.DelegatorAddress, del.ValidatorAddress}
+		shares, ok := sharesA[key]
+		switch {
+		case !ok:
+			d.Delegations.Added++
+		case !shares.Equal(del.Shares):
+			d.Delegations.Changed++
+		}
+		delete(sharesA, key)
+	}
+	d.Delegations.Removed = len(sharesA)
+
+	d.Summary = append(d.Summary,
+		SummaryCount{Name: "validators", Old: len(stakingA.Validators), New: len(stakingB.Validators)},
+		SummaryCount{Name: "delegations", Old: len(stakingA.Delegations), New: len(stakingB.Delegations)},
+		SummaryCount{Name: "unbonding_delegations", Old: len(stakingA.UnbondingDelegations), New: len(stakingB.UnbondingDelegations)},
+		SummaryCount{Name: "redelegations", Old: len(stakingA.Redelegations), New: len(stakingB.Redelegations)},
+	)
+	d.changed[stakingtypes.ModuleName] = len(d.Validators.Added) > 0 || len(d.Validators.Removed) > 0 || len(d.Validators.Changed) > 0 ||
+		d.Delegations.Rewritten() > 0
+	return nil
+}
+
+// diffParams compares every top level field whose name ends with "params",
+// such as gov.voting_params, key by key. A module or a params field missing on
+// one side is compared as an empty params object.
+func (d *GenesisDiff) diffParams(genStateA, genStateB map[string]json.RawMessage) error {
+	for _, module := range unionKeys(genStateA, genStateB) {
+		var fieldsA, fieldsB map[string]json.RawMessage
+		if err := unmarshalIfPresent(genStateA[module], &fieldsA); err != nil {
+			continue
+		}
+		if err := unmarshalIfPresent(genStateB[module], &fieldsB); err != nil {
+			continue
+		}
+
+		for _, field := range unionKeys(fieldsA, fieldsB) {
+			if !strings.HasSuffix(field, "params") {
+				continue
+			}
+
+			var paramsA, paramsB map[string]json.RawMessage
+			if err := unmarshalIfPresent(fieldsA[field], &paramsA); err != nil {
+				return fmt.Errorf("failed to decode %s.%s: %w", module, field, err)
+			}
+			if err := unmarshalIfPresent(fieldsB[field], &paramsB); err != nil {
+				return fmt.Errorf("failed to decode %s.%s: %w", module, field, err)
+			}
+
+			for _, k := range unionKeys(paramsA, paramsB) {
+				if !jsonEqual(paramsA[k], paramsB[k]) {
+					d.Params = append(d.Params, FieldChange{
+						Field: module + "." + field + "." + k,
+						Old:   string(compactJSON(paramsA[k])),
+						New:   string(compactJSON(paramsB[k])),
+					})
+					d.changed[module] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unmarshalIfPresent decodes bz into v, leaving v untouched when bz is empty.
+func unmarshalIfPresent(bz json.RawMessage, v interface{}) error {
+	if len(bz) == 0 {
+		return nil
+	}
+	return json.Unmarshal(bz, v)
+}
+
+// writeText writes a human readable rendering of the diff to w.
+func (d GenesisDiff) writeText(w io.Writer) {
+	for _, f := range d.Header {
+		fmt.Fprintf(w, "%s: %s -> %s\n", f.Field, f.Old, f.New)
+	}
+	for _, module := range d.Modules.Added {
+		fmt.Fprintf(w, "module added: %s\n", module)
+	}
+	for _, module := range d.Modules.Removed {
+		fmt.Fprintf(w, "module removed: %s\n", module)
+	}
+
+	fmt.Fprintln(w, "summary:")
+	for _, c := range d.Summary {
+		fmt.Fprintf(w, "  %s: %d -> %d\n", c.Name, c.Old, c.New)
+	}
+
+	fmt.Fprintf(w, "accounts: %d added, %d removed\n", len(d.Accounts.Added), len(d.Accounts.Removed))
+	for _, addr := range d.Accounts.Added {
+		fmt.Fprintf(w, "  + %s\n", addr)
+	}
+	for _, addr := range d.Accounts.Removed {
+		fmt.Fprintf(w, "  - %s\n", addr)
+	}
+
+	fmt.Fprintf(w, "balances: %d changed\n", len(d.Balances))
+	for _, b := range d.Balances {
+		fmt.Fprintf(w, "  %s:%s\n", b.Address, formatDeltas(b.Deltas))
+	}
+
+	fmt.Fprintln(w, "supply:")
+	for _, delta := range d.Supply {
+		fmt.Fprintf(w, "  %s: %s -> %s (%s)\n", delta.Denom, delta.Old, delta.New, signedInt(delta.Delta()))
+	}
+
+	fmt.Fprintf(w, "validators: %d added, %d removed, %d changed\n", len(d.Validators.Added), len(d.Validators.Removed), len(d.Validators.Changed))
+	for _, operator := range d.Validators.Added {
+		fmt.Fprintf(w, "  + %s\n", operator)
+	}
+	for _, operator := range d.Validators.Removed {
+		fmt.Fprintf(w, "  - %s\n", operator)
+	}
+	for _, c := range d.Validators.Changed {
+		fmt.Fprintf(w, "  ~ %s\n", c.Operator)
+		for _, f := range c.Fields {
+			fmt.Fprintf(w, "      %s: %s -> %s\n", f.Field, f.Old, f.New)
+		}
+	}
+
+	fmt.Fprintf(w, "delegations: %d added, %d removed, %d changed\n", d.Delegations.Added, d.Delegations.Removed, d.Delegations.Changed)
+
+	fmt.Fprintf(w, "params: %d changed\n", len(d.Params))
+	for _, f := range d.Params {
+		fmt.Fprintf(w, "  %s: %s -> %s\n", f.Field, f.Old, f.New)
+	}
+
+	if len(d.Unchanged) > 0 {
+		fmt.Fprintf(w, "unchanged modules: %s\n", strings.Join(d.Unchanged, ", "))
+	}
+}
+
+// accountJSON holds the address of any auth account type, which is either at
+// the top level or in an embedded base account.
+type accountJSON struct {
+	Address            string       `json:"address"`
+	BaseAccount        *accountJSON `json:"base_account"`
+	BaseVestingAccount *accountJSON `json:"base_vesting_account"`
+}
+
+func (a accountJSON) address() string {
+	switch {
+	case a.Address != "":
+		return a.Address
+	case a.BaseAccount != nil:
+		return a.BaseAccount.address()
+	case a.BaseVestingAccount != nil:
+		return a.BaseVestingAccount.address()
+	default:
+		return ""
+	}
+}
+
+// accountAddresses returns the set of auth account addresses of genState. The
+// accounts are decoded as plain JSON so that addresses of any bech32 prefix
+// are returned.
+func accountAddresses(genState map[string]json.RawMessage) (map[string]bool, error) {
+	var authGenesis struct {
+		Accounts []accountJSON `json:"accounts"`
+	}
+	if err := json.Unmarshal(genState[authtypes.ModuleName], &authGenesis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal auth genesis state: %w", err)
+	}
+
+	addresses := make(map[string]bool, len(authGenesis.Accounts))
+	for _, acc := range authGenesis.Accounts {
+		addresses[acc.address()] = true
+	}
+	return addresses, nil
+}
+
+// diffValidator returns the fields of interest that differ between a and b.
+func diffValidator(a, b stakingtypes.Validator) []FieldChange {
+	var fields []FieldChange
+	fields = diffFields(fields, "moniker", a.Description.Moniker, b.Description.Moniker)
+	fields = diffFields(fields, "status", a.Status.String(), b.Status.String())
+	fields = diffFields(fields, "jailed", fmt.Sprint(a.Jailed), fmt.Sprint(b.Jailed))
+	fields = diffFields(fields, "tokens", a.Tokens.String(), b.Tokens.String())
+	fields = diffFields(fields, "delegator_shares", a.DelegatorShares.String(), b.DelegatorShares.String())
+	fields = diffFields(fields, "commission_rate", a.Commission.Rate.String(), b.Commission.Rate.String())
+	fields = diffFields(fields, "min_self_delegation", a.MinSelfDelegation.String(), b.MinSelfDelegation.String())
+
+	fields = diffFields(fields, "consensus_pubkey", consPubKeyString(a), consPubKeyString(b))
+	return fields
+}
+
+// consPubKeyString returns the base64 encoded consensus pubkey of val.
+func consPubKeyString(val stakingtypes.Validator) string {
+	pk, err := val.ConsPubKey()
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(pk.Bytes())
+}
+
+// diffFields appends a FieldChange to fields if old and new differ.
+func diffFields(fields []FieldChange, field, old, new string) []FieldChange {
+	if old == new {
+		return fields
+	}
+	return append(fields, FieldChange{Field: field, Old: old, New: new})
+}
+
+// diffCoins returns the denoms whose amount differs between a and b.
+func diffCoins(a, b sdk.Coins) []DenomDelta {
+	var deltas []DenomDelta
+	for _, denom := range unionDenoms(a, b) {
+		if old, new := a.AmountOf(denom), b.AmountOf(denom); !old.Equal(new) {
+			deltas = append(deltas, DenomDelta{Denom: denom, Old: old, New: new})
+		}
+	}
+	return deltas
+}
+
+func formatDeltas(deltas []DenomDelta) string {
+	var sb strings.Builder
+	for _, delta := range deltas {
+		fmt.Fprintf(&sb, " %s%s", signedInt(delta.Delta()), delta.Denom)
+	}
+	return sb.String()
+}
+
+func signedInt(i sdk.Int) string {
+	if i.IsNegative() {
+		return i.String()
+	}
+	return "+" + i.String()
+}
+
+// missingKeys returns the sorted keys of a missing from b.
+func missingKeys(a, b map[string]bool) []string {
+	var keys []string
+	for k := range a {
+		if !b[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unionKeys returns the sorted keys present in any of the given objects.
+func unionKeys(objects ...map[string]json.RawMessage) []string {
+	keys := make(map[string]bool)
+	for _, obj := range objects {
+		for k := range obj {
+			keys[k] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// jsonEqual reports whether a and b hold the same JSON value, ignoring
+// formatting and object key order.
+func jsonEqual(a, b json.RawMessage) bool {
+	va, errA := decodeJSON(a)
+	vb, errB := decodeJSON(b)
+	if errA != nil || errB != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func decodeJSON(bz json.RawMessage) (interface{}, error) {
+	if len(bz) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func compactJSON(bz json.RawMessage) []byte {
+	if len(bz) == 0 {
+		return []byte("null")
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, bz); err != nil {
+		return bz
+	}
+	return buf.Bytes()
+}
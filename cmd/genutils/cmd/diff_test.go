@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffParams(t *testing.T) {
+	const mint = `{"minter":{"inflation":"0.1"},"params":{"mint_denom":"ubtsg","blocks_per_year":"6311520"}}`
+
+	tests := []struct {
+		name        string
+		a, b        map[string]string
+		want        []FieldChange
+		wantChanged []string
+		wantErr     bool
+	}{
+		{
+			name: "unchanged",
+			a:    map[string]string{"mint": mint},
+			b:    map[string]string{"mint": mint},
+		},
+		{
+			name: "changed key",
+			a:    map[string]string{"gov": `{"voting_params":{"voting_period":"172800s"},"proposals":[]}`},
+			b:    map[string]string{"gov": `{"voting_params":{"voting_period":"600s"},"proposals":[1]}`},
+			want: []FieldChange{
+				{Field: "gov.voting_params.voting_period", Old: `"172800s"`, New: `"600s"`},
+			},
+			wantChanged: []string{"gov"},
+		},
+		{
+			name: "params only in a",
+			a:    map[string]string{"mint": mint},
+			b:    map[string]string{"mint": `{"minter":{"inflation":"0.1"}}`},
+			want: []FieldChange{
+				{Field: "mint.params.blocks_per_year", Old: `"6311520"`, New: "null"},
+				{Field: "mint.params.mint_denom", Old: `"ubtsg"`, New: "null"},
+			},
+			wantChanged: []string{"mint"},
+		},
+		{
+			name: "params only in b",
+			a:    map[string]string{"mint": `{"minter":{"inflation":"0.1"}}`},
+			b:    map[string]string{"mint": mint},
+			want: []FieldChange{
+				{Field: "mint.params.blocks_per_year", Old: "null", New: `"6311520"`},
+				{Field: "mint.params.mint_denom", Old: "null", New: `"ubtsg"`},
+			},
+			wantChanged: []string{"mint"},
+		},
+		{
+			name: "module only in b",
+			a:    map[string]string{},
+			b:    map[string]string{"mint": mint},
+			want: []FieldChange{
+				{Field: "mint.params.blocks_per_year", Old: "null", New: `"6311520"`},
+				{Field: "mint.params.mint_denom", Old: "null", New: `"ubtsg"`},
+			},
+			wantChanged: []string{"mint"},
+		},
+		{
+			name:    "params that are not an object",
+			a:       map[string]string{"mint": `{"params":[1]}`},
+			b:       map[string]string{"mint": mint},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			toGenState := func(states map[string]string) map[string]json.RawMessage {
+				genState := make(map[string]json.RawMessage, len(states))
+				for module, state := range states {
+					genState[module] = json.RawMessage(state)
+				}
+				return genState
+			}
+
+			d := GenesisDiff{changed: make(map[string]bool)}
+			err := d.diffParams(toGenState(tc.a), toGenState(tc.b))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, d.Params)
+
+			var changed []string
+			for module := range d.changed {
+				changed = append(changed, module)
+			}
+			require.ElementsMatch(t, tc.wantChanged, changed)
+		})
+	}
+}
@@ -3,24 +3,24 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/server"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
-	"github.com/cosmos/cosmos-sdk/x/staking/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/spf13/cobra"
-	"github.com/tendermint/tendermint/crypto/secp256k1"
-	tmjson "github.com/tendermint/tendermint/libs/json"
 	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/go-btsg/genutils/genesis"
 )
 
 type DeriveSnapshotStaked struct {
@@ -34,321 +34,497 @@ type StakedAccount struct {
 	UsdValue sdk.Int `json:"usd_value"`
 }
 
+// AssetInfo is the USD price of one whole unit of a denom whose base unit has
+// Decimals decimals, e.g. 6 for ubtsg.
 type AssetInfo struct {
-	denom   string
-	price   sdk.Dec
-	decimal int64
+	Denom    string  `json:"denom"`
+	Price    sdk.Dec `json:"price"`
+	Decimals int64   `json:"decimals"`
 }
 
-func getGenStateFromPath(genesisFilePath string) (tmtypes.GenesisDoc, map[string]json.RawMessage, error) {
-	genState := make(map[string]json.RawMessage)
-	var doc tmtypes.GenesisDoc
-
-	genesisFile, err := os.Open(genesisFilePath)
-	if err != nil {
-		return doc, genState, err
-	}
-	defer genesisFile.Close()
-
-	byteValue, _ := ioutil.ReadAll(genesisFile)
-
-	err = tmjson.Unmarshal(byteValue, &doc)
-	if err != nil {
-		return doc, genState, err
-	}
+const (
+	flagRecipe              = "recipe"
+	flagPreserveDelegations = "preserve-delegations"
+	flagGenesisTime         = "genesis-time"
+	flagInitialHeight       = "initial-height"
+	flagBackup              = "backup"
+)
 
-	err = json.Unmarshal(doc.AppState, &genState)
-	if err != nil {
-		panic(err)
-	}
-	return doc, genState, nil
+// addBackupFlag registers the --backup flag read by writeGenesisFile.
+func addBackupFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(flagBackup, false, "Keep the previous output genesis file, if any, with a "+genesis.BackupSuffix+" suffix")
 }
 
-func writeGenStateToPath(doc tmtypes.GenesisDoc, path string, genState map[string]json.RawMessage) error {
-	f, err := os.Create(path)
-	defer f.Close()
-
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-
-	genStateBz, err := json.MarshalIndent(genState, "", " ")
-	if err != nil {
-		return err
-	}
-	doc.AppState = genStateBz
-
-	byteValue, err := tmjson.Marshal(&doc)
+// writeGenesisFile writes the output genesis file of cmd, honoring --backup.
+func writeGenesisFile(cmd *cobra.Command, g *genesis.Genesis, path string) error {
+	backup, err := cmd.Flags().GetBool(flagBackup)
 	if err != nil {
 		return err
 	}
-
-	ioutil.WriteFile(path, byteValue, 0644)
-	return nil
+	return g.Save(path, backup)
 }
 
 func ExportUpgradedGenesisCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "export-upgraded-genesis [input-genesis-file] [new_val_owner] [new_val_operator] [new_val_pubkey_json] [output-genesis-file]",
 		Short: "Export upgraded genesis from a provided genesis export",
-		Long: `Export upgraded genesis from a provided genesis export
+		Long: `Export upgraded genesis from a provided genesis export.
+
+The fork can either be described by the positional arguments below or by a
+YAML/JSON recipe file passed with --recipe, in which case only the input and
+output genesis files are given as arguments.
+
+With --preserve-delegations the exported delegations and unbonding delegations
+are kept and re-pointed to the new validators, following the validator_map of
+the recipe and assigning unmapped validators round-robin.
+
+The chain id, genesis time and initial height of the new genesis are taken
+from the recipe or from --chain-id, --genesis-time and --initial-height, and
+are otherwise kept from the export. The genesis time is either an RFC 3339
+time or a time relative to now such as "now+24h". The resulting genesis doc is
+validated before it is written.
+
 Example:
 	genutils export-upgraded-genesis bitsong_export.json bitsong13m350fvnk3s6y5n8ugxhmka277r0t7cw48ru47 bitsongvaloper13m350fvnk3s6y5n8ugxhmka277r0t7cw5rl49r '{"@type":"/cosmos.crypto.ed25519.PubKey","key":"Dst4aT7mWIUriAO5IrGAxMoLh+ratiG92DHCOSZ8rAo="}' new-bitsong-genesis.json
+	genutils export-upgraded-genesis bitsong_export.json new-bitsong-genesis.json --recipe fork.yaml
+	genutils export-upgraded-genesis bitsong_export.json new-bitsong-genesis.json --recipe fork.yaml --chain-id bitsong-fork-1 --genesis-time now+1h --initial-height 1
 `,
-		Args: cobra.ExactArgs(5),
+		Args: func(cmd *cobra.Command, args []string) error {
+			recipePath, err := cmd.Flags().GetString(flagRecipe)
+			if err != nil {
+				return err
+			}
+			if recipePath != "" {
+				return cobra.ExactArgs(2)(cmd, args)
+			}
+			return cobra.ExactArgs(5)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			clientCtx := client.GetClientContextFromCmd(cmd)
 			serverCtx := server.GetServerContextFromCmd(cmd)
 			config := serverCtx.Config
 			config.SetRoot(clientCtx.HomeDir)
 
-			genesisFile := args[0]
-			newValOwner := args[1]
-			newValOperator := args[2]
-			newValPubKey := args[3]
-			newGenesisOutput := args[4]
-			doc, genState, err := getGenStateFromPath(genesisFile)
+			recipePath, err := cmd.Flags().GetString(flagRecipe)
 			if err != nil {
 				return err
 			}
 
-			authGenesis := authtypes.GenesisState{}
-			clientCtx.Codec.MustUnmarshalJSON(genState["auth"], &authGenesis)
-			accounts, err := authtypes.UnpackAccounts(authGenesis.Accounts)
-			if err != nil {
-				panic(err)
+			var recipe ForkRecipe
+			genesisFile := args[0]
+			newGenesisOutput := args[len(args)-1]
+			if recipePath != "" {
+				recipe, err = loadForkRecipe(recipePath)
+				if err != nil {
+					return err
+				}
+			} else {
+				recipe = newLegacyForkRecipe(args[1], args[2], args[3])
 			}
-			accounts = authtypes.SanitizeGenesisAccounts(accounts)
 
-			// add new account into auth.Accounts
-			accValOwner, err := sdk.AccAddressFromBech32(newValOwner)
-			if err != nil {
+			if cmd.Flags().Changed(flagPreserveDelegations) {
+				recipe.PreserveDelegations, err = cmd.Flags().GetBool(flagPreserveDelegations)
+				if err != nil {
+					return err
+				}
+			}
+			if err := forkHeaderFromFlags(cmd, &recipe); err != nil {
 				return err
 			}
-			accounts = append(accounts, authtypes.NewBaseAccount(accValOwner, nil, 0, 0))
 
-			packedAccs, err := authtypes.PackAccounts(accounts)
+			g, err := genesis.Load(clientCtx.Codec, genesisFile)
 			if err != nil {
 				return err
 			}
-			authGenesis.Accounts = packedAccs
-			authGenesisBz := clientCtx.Codec.MustMarshalJSON(&authGenesis)
-			genState["auth"] = authGenesisBz
-
-			// add balances object into bank.Balances
-			bankGenesis := banktypes.GenesisState{}
-			clientCtx.JSONCodec.MustUnmarshalJSON(genState["bank"], &bankGenesis)
-			bankCoins := sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 1000_000_000))
-			var bondedCoins sdk.Coins
-			var notBondedCoins sdk.Coins
-			bankGenesis.Supply = bankGenesis.Supply.Add(bankCoins...)
-			bankGenesis.Balances = append(bankGenesis.Balances, banktypes.Balance{
-				Address: newValOwner,
-				Coins:   bankCoins,
-			})
+			before := g.Clone()
 
-			bondedPoolAddr := authtypes.NewModuleAddress(stakingtypes.BondedPoolName)
-			notBondedPoolAddr := authtypes.NewModuleAddress(stakingtypes.NotBondedPoolName)
-			fmt.Println("bondedPoolAddr", bondedPoolAddr.String())
-			for _, balance := range bankGenesis.Balances {
-				if balance.Address == bondedPoolAddr.String() {
-					fmt.Println("bondedPoolAddr-match-found", bondedPoolAddr.String(), balance.Coins.String())
-					bondedCoins = balance.Coins
-				}
-				if balance.Address == notBondedPoolAddr.String() {
-					fmt.Println("notBondedPoolAddr-match-found", notBondedPoolAddr.String(), balance.Coins.String())
-					notBondedCoins = balance.Coins
-				}
+			if err := applyForkRecipe(g, recipe); err != nil {
+				return err
 			}
 
-			bankGenesisBz := clientCtx.JSONCodec.MustMarshalJSON(&bankGenesis)
-			genState["bank"] = bankGenesisBz
+			if err := fixSupply(cmd, g); err != nil {
+				return err
+			}
 
 			// TODO: think of removing genutil.GenTxs
-			// TODO: try starting the chain with new genesis
-
-			stakingGenesis := stakingtypes.GenesisState{}
-			clientCtx.JSONCodec.MustUnmarshalJSON(genState["staking"], &stakingGenesis)
 
-			var pubKey cryptotypes.PubKey
-			if err := clientCtx.Codec.UnmarshalInterfaceJSON([]byte(newValPubKey), &pubKey); err != nil {
-				panic(err)
+			// export snapshot json
+			if err := exportGenesisFile(cmd, g, newGenesisOutput); err != nil {
+				return err
 			}
 
-			var pkAny *codectypes.Any
-			if pubKey != nil {
-				var err error
-				if pkAny, err = codectypes.NewAnyWithValue(pubKey); err != nil {
-					panic(err)
-				}
-			}
+			return writeChangeReport(cmd, before, g, genesisFile, newGenesisOutput)
+		},
+	}
+
+	cmd.Flags().String(flagRecipe, "", "YAML or JSON file describing the fork (replaces the validator arguments)")
+	cmd.Flags().Bool(flagPreserveDelegations, false, "Keep the exported delegations and re-point them to the new validators")
+	cmd.Flags().String(flags.FlagChainID, "", "Chain id of the new genesis (overrides the recipe)")
+	cmd.Flags().String(flagGenesisTime, "", `Genesis time of the new genesis, either RFC 3339 or relative to now such as "now+24h" (overrides the recipe)`)
+	cmd.Flags().Int64(flagInitialHeight, 0, "Initial height of the new genesis (overrides the recipe)")
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// forkHeaderFromFlags sets the chain id, genesis time and initial height of
+// recipe from the flags that are given, then validates the recipe again.
+func forkHeaderFromFlags(cmd *cobra.Command, recipe *ForkRecipe) error {
+	var err error
+	if cmd.Flags().Changed(flags.FlagChainID) {
+		if recipe.ChainID, err = cmd.Flags().GetString(flags.FlagChainID); err != nil {
+			return err
+		}
+	}
+	if cmd.Flags().Changed(flagGenesisTime) {
+		if recipe.GenesisTime, err = cmd.Flags().GetString(flagGenesisTime); err != nil {
+			return err
+		}
+	}
+	if cmd.Flags().Changed(flagInitialHeight) {
+		if recipe.InitialHeight, err = cmd.Flags().GetInt64(flagInitialHeight); err != nil {
+			return err
+		}
+	}
+	return recipe.Validate()
+}
+
+// applyForkRecipe replaces the validator set of an exported state with the
+// validators of the recipe and funds the recipe accounts.
+func applyForkRecipe(g *genesis.Genesis, recipe ForkRecipe) error {
+	if recipe.ChainID != "" {
+		g.Doc.ChainID = recipe.ChainID
+	}
+	if recipe.GenesisTime != "" {
+		genesisTime, err := parseGenesisTime(recipe.GenesisTime, time.Now())
+		if err != nil {
+			return err
+		}
+		g.Doc.GenesisTime = genesisTime
+	}
+	if recipe.InitialHeight != 0 {
+		g.Doc.InitialHeight = recipe.InitialHeight
+	}
+
+	// collect every balance added by the recipe, starting with the validator owners
+	var funds []banktypes.Balance
+	for _, val := range recipe.Validators {
+		selfBalance, err := sdk.ParseCoinsNormalized(val.SelfBalance)
+		if err != nil {
+			return fmt.Errorf("failed to parse self balance of %s: %w", val.Operator, err)
+		}
+		funds = append(funds, banktypes.Balance{Address: val.Owner, Coins: selfBalance})
+	}
+	for _, acc := range recipe.Accounts {
+		coins, err := sdk.ParseCoinsNormalized(acc.Coins)
+		if err != nil {
+			return fmt.Errorf("failed to parse coins of %s: %w", acc.Address, err)
+		}
+		funds = append(funds, banktypes.Balance{Address: acc.Address, Coins: coins})
+	}
+
+	accounts, err := g.Accounts()
+	if err != nil {
+		return err
+	}
 
-			valPriv2 := secp256k1.GenPrivKey().PubKey()
-			valOper2 := sdk.ValAddress(valPriv2.Address())
-			var pk2Any *codectypes.Any
-			if pk2Any, err = codectypes.NewAnyWithValue(pubKey); err != nil {
-				panic(err)
+	// add new accounts into auth.Accounts
+	for _, fund := range funds {
+		addr, err := sdk.AccAddressFromBech32(fund.Address)
+		if err != nil {
+			return err
+		}
+		if !accounts.Contains(addr) {
+			accounts = append(accounts, authtypes.NewBaseAccount(addr, nil, 0, 0))
+		}
+	}
+	if err := g.SetAccounts(authtypes.SanitizeGenesisAccounts(accounts)); err != nil {
+		return err
+	}
+
+	// add balances objects into bank.Balances
+	bankGenesis, err := g.Bank()
+	if err != nil {
+		return err
+	}
+	for _, fund := range funds {
+		if err := adjustSupply(&bankGenesis, fund.Coins, nil); err != nil {
+			return err
+		}
+		bankGenesis.Balances = addBalance(bankGenesis.Balances, fund)
+	}
+
+	bondedPoolAddr := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+	notBondedPoolAddr := authtypes.NewModuleAddress(stakingtypes.NotBondedPoolName).String()
+	bondedTokens := balanceOf(bankGenesis.Balances, bondedPoolAddr).AmountOf(recipe.Denom)
+	notBondedTokens := balanceOf(bankGenesis.Balances, notBondedPoolAddr).AmountOf(recipe.Denom)
+
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return err
+	}
+
+	newValidators := make([]stakingtypes.Validator, len(recipe.Validators))
+	var previousProposer sdk.ConsAddress
+	for i, val := range recipe.Validators {
+		commission, err := val.Commission.toCommission()
+		if err != nil {
+			return err
+		}
+
+		var pubKey cryptotypes.PubKey
+		if err := g.Codec().UnmarshalInterfaceJSON(val.pubKeyJSON(), &pubKey); err != nil {
+			return fmt.Errorf("failed to unmarshal pubkey of %s: %w", val.Operator, err)
+		}
+		pkAny, err := codectypes.NewAnyWithValue(pubKey)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			previousProposer = sdk.ConsAddress(pubKey.Address())
+		}
+
+		newValidators[i] = stakingtypes.Validator{
+			OperatorAddress:   val.Operator,
+			ConsensusPubkey:   pkAny,
+			Jailed:            false,
+			Status:            stakingtypes.Bonded,
+			Tokens:            sdk.ZeroInt(),
+			DelegatorShares:   sdk.ZeroDec(),
+			Description:       stakingtypes.NewDescription(val.Moniker, "", "", "", ""),
+			UnbondingHeight:   0,
+			UnbondingTime:     time.Time{},
+			Commission:        commission,
+			MinSelfDelegation: sdk.NewInt(1),
+		}
+	}
+
+	if recipe.PreserveDelegations {
+		if err := repointDelegations(&stakingGenesis, newValidators, recipe.ValidatorMap); err != nil {
+			return err
+		}
+
+		// tokens of old unbonded validators are now bonded, so move them from
+		// the not bonded pool to the bonded pool
+		newBondedTokens := sdk.ZeroInt()
+		for _, val := range stakingGenesis.Validators {
+			newBondedTokens = newBondedTokens.Add(val.Tokens)
+		}
+		newNotBondedTokens := sdk.ZeroInt()
+		for _, ubd := range stakingGenesis.UnbondingDelegations {
+			for _, entry := range ubd.Entries {
+				newNotBondedTokens = newNotBondedTokens.Add(entry.Balance)
 			}
-			fmt.Println("unbondedValidator", valOper2.String())
-
-			stakingGenesis.Exported = false
-			stakingGenesis.Validators = []stakingtypes.Validator{{
-				OperatorAddress:   newValOperator,
-				ConsensusPubkey:   pkAny,
-				Jailed:            false,
-				Status:            stakingtypes.Bonded,
-				Tokens:            bondedCoins.AmountOf("ubtsg"),
-				DelegatorShares:   bondedCoins.AmountOf("ubtsg").ToDec(),
-				Description:       stakingtypes.NewDescription("moniker1", "", "", "", ""),
-				UnbondingHeight:   0,
-				UnbondingTime:     time.Time{},
-				Commission:        types.NewCommission(sdk.NewDecWithPrec(1, 2), sdk.NewDecWithPrec(10, 2), sdk.NewDecWithPrec(1, 2)),
-				MinSelfDelegation: sdk.NewInt(1),
-			}, {
-				OperatorAddress:   valOper2.String(),
-				ConsensusPubkey:   pk2Any,
-				Jailed:            false,
+		}
+		if !newBondedTokens.Add(newNotBondedTokens).Equal(bondedTokens.Add(notBondedTokens)) {
+			return fmt.Errorf("staking pools hold %s%s but validators and unbonding delegations hold %s%s",
+				bondedTokens.Add(notBondedTokens), recipe.Denom, newBondedTokens.Add(newNotBondedTokens), recipe.Denom)
+		}
+
+		bankGenesis.Balances = setBalanceAmount(bankGenesis.Balances, bondedPoolAddr, sdk.NewCoin(recipe.Denom, newBondedTokens))
+		bankGenesis.Balances = setBalanceAmount(bankGenesis.Balances, notBondedPoolAddr, sdk.NewCoin(recipe.Denom, newNotBondedTokens))
+	} else {
+		stakes := splitByWeight(bondedTokens, recipe.validatorWeights())
+		stakingGenesis.Validators = newValidators
+		stakingGenesis.Delegations = []stakingtypes.Delegation{}
+		for i, val := range recipe.Validators {
+			stakingGenesis.Validators[i].Tokens = stakes[i]
+			stakingGenesis.Validators[i].DelegatorShares = stakes[i].ToDec()
+			stakingGenesis.Delegations = append(stakingGenesis.Delegations, stakingtypes.Delegation{
+				DelegatorAddress: val.Owner,
+				ValidatorAddress: val.Operator,
+				Shares:           stakes[i].ToDec(),
+			})
+		}
+		stakingGenesis.UnbondingDelegations = []stakingtypes.UnbondingDelegation{}
+		stakingGenesis.Redelegations = []stakingtypes.Redelegation{}
+
+		// the not bonded pool is held by a validator with a throwaway key so
+		// that the pool balance keeps matching the unbonded tokens. It is
+		// jailed so that InitChain does not bond it.
+		if notBondedTokens.IsPositive() {
+			unbondedPubKey := ed25519.GenPrivKey().PubKey()
+			unbondedOper := sdk.ValAddress(unbondedPubKey.Address())
+			unbondedPkAny, err := codectypes.NewAnyWithValue(unbondedPubKey)
+			if err != nil {
+				return err
+			}
+
+			stakingGenesis.Validators = append(stakingGenesis.Validators, stakingtypes.Validator{
+				OperatorAddress:   unbondedOper.String(),
+				ConsensusPubkey:   unbondedPkAny,
+				Jailed:            true,
 				Status:            stakingtypes.Unbonded,
-				Tokens:            notBondedCoins.AmountOf("ubtsg"),
-				DelegatorShares:   notBondedCoins.AmountOf("ubtsg").ToDec(),
-				Description:       stakingtypes.NewDescription("moniker2", "", "", "", ""),
+				Tokens:            notBondedTokens,
+				DelegatorShares:   notBondedTokens.ToDec(),
+				Description:       stakingtypes.NewDescription(recipe.UnbondedMoniker, "", "", "", ""),
 				UnbondingHeight:   0,
 				UnbondingTime:     time.Time{},
-				Commission:        types.NewCommission(sdk.NewDecWithPrec(1, 2), sdk.NewDecWithPrec(10, 2), sdk.NewDecWithPrec(1, 2)),
+				Commission:        newValidators[0].Commission,
 				MinSelfDelegation: sdk.NewInt(1),
-			}}
-
-			stakingGenesis.Delegations = []stakingtypes.Delegation{{
-				DelegatorAddress: newValOwner,
-				ValidatorAddress: newValOperator,
-				Shares:           sdk.NewDec(100000000),
-			}}
-			stakingGenesis.UnbondingDelegations = []stakingtypes.UnbondingDelegation{}
-
-			stakingGenesisBz := clientCtx.JSONCodec.MustMarshalJSON(&stakingGenesis)
-			genState["staking"] = stakingGenesisBz
-
-			// update distribution genesis
-			distrGenesis := distrtypes.GenesisState{}
-			clientCtx.JSONCodec.MustUnmarshalJSON(genState["distribution"], &distrGenesis)
-
-			distrGenesis.DelegatorStartingInfos = []distrtypes.DelegatorStartingInfoRecord{
-				{
-					DelegatorAddress: newValOwner,
-					ValidatorAddress: newValOperator,
-					StartingInfo: distrtypes.DelegatorStartingInfo{
-						Height:         0,
-						PreviousPeriod: 1,
-						Stake:          bondedCoins.AmountOf("ubtsg").ToDec(),
-					},
-				},
-				{
-					DelegatorAddress: sdk.AccAddress(valOper2).String(),
-					ValidatorAddress: valOper2.String(),
-					StartingInfo: distrtypes.DelegatorStartingInfo{
-						Height:         0,
-						PreviousPeriod: 1,
-						Stake:          notBondedCoins.AmountOf("ubtsg").ToDec(),
-					},
-				},
-			}
-			distrGenesis.DelegatorWithdrawInfos = []distrtypes.DelegatorWithdrawInfo{}
-			distrGenesis.OutstandingRewards = []distrtypes.ValidatorOutstandingRewardsRecord{}
-			distrGenesis.PreviousProposer = sdk.ConsAddress(pubKey.Address()).String()
-			distrGenesis.ValidatorAccumulatedCommissions = []distrtypes.ValidatorAccumulatedCommissionRecord{}
-			distrGenesis.ValidatorCurrentRewards = []distrtypes.ValidatorCurrentRewardsRecord{}
-			distrGenesis.ValidatorHistoricalRewards = []distrtypes.ValidatorHistoricalRewardsRecord{}
-			distrGenesis.ValidatorSlashEvents = []distrtypes.ValidatorSlashEventRecord{}
-
-			distrGenesisBz := clientCtx.JSONCodec.MustMarshalJSON(&distrGenesis)
-			genState["distribution"] = distrGenesisBz
-
-			// 			genState["distribution"] = []byte(fmt.Sprintf(`{
-			//   "delegator_starting_infos": [
-			//     {
-			//       "delegator_address": "%s",
-			//       "starting_info": {
-			//         "height": "0",
-			//         "previous_period": "1",
-			//         "stake": "100000000.000000000000000000"
-			//       },
-			//       "validator_address": "%s"
-			//     }
-			//   ],
-			//   "delegator_withdraw_infos": {},
-			//   "fee_pool": {
-			//     "community_pool": [
-			//       {
-			//         "amount": "110908.340000000000000000",
-			//         "denom": "ubtsg"
-			//       }
-			//     ]
-			//   },
-			//   "outstanding_rewards": [
-			//     {
-			//       "outstanding_rewards": [
-			//         {
-			//           "amount": "5434508.660000000000000000",
-			//           "denom": "ubtsg"
-			//         }
-			//       ],
-			//       "validator_address": "%s"
-			//     }
-			//   ],
-			//   "params": {
-			//     "base_proposer_reward": "0.010000000000000000",
-			//     "bonus_proposer_reward": "0.040000000000000000",
-			//     "community_tax": "0.020000000000000000",
-			//     "withdraw_addr_enabled": true
-			//   },
-			//   "previous_proposer": "%s",
-			//   "validator_accumulated_commissions": [
-			//     {
-			//       "accumulated": {
-			//         "commission": [
-			//           {
-			//             "amount": "543450.866000000000000000",
-			//             "denom": "ubtsg"
-			//           }
-			//         ]
-			//       },
-			//       "validator_address": "%s"
-			//     }
-			//   ],
-			//   "validator_current_rewards": [
-			//     {
-			//       "rewards": {
-			//         "period": "2",
-			//         "rewards": [
-			//           {
-			//             "amount": "4891057.794000000000000000",
-			//             "denom": "ubtsg"
-			//           }
-			//         ]
-			//       },
-			//       "validator_address": "%s"
-			//     }
-			//   ],
-			//   "validator_historical_rewards": [
-			//     {
-			//       "period": "1",
-			//       "rewards": {
-			//         "cumulative_reward_ratio": {},
-			//         "reference_count": 2
-			//       },
-			//       "validator_address": "%s"
-			//     }
-			//   ],
-			//   "validator_slash_events": {}
-			// }`, newValOwner, newValOperator, newValOperator, sdk.ConsAddress(pubKey.Address()).String(), newValOperator,
-			// 				newValOperator, newValOperator))
+			})
+			stakingGenesis.Delegations = append(stakingGenesis.Delegations, stakingtypes.Delegation{
+				DelegatorAddress: sdk.AccAddress(unbondedOper).String(),
+				ValidatorAddress: unbondedOper.String(),
+				Shares:           notBondedTokens.ToDec(),
+			})
+		}
+	}
 
-			// export snapshot json
-			writeGenStateToPath(doc, newGenesisOutput, genState)
+	if err := g.SetBank(bankGenesis); err != nil {
+		return err
+	}
 
+	// the validators of the genesis doc must match the set returned by
+	// InitChain, so they are rebuilt from the new bonded validators
+	powerReduction := sdk.DefaultPowerReduction
+	stakingGenesis.Exported = false
+	stakingGenesis.LastValidatorPowers = []stakingtypes.LastValidatorPower{}
+	stakingGenesis.LastTotalPower = sdk.ZeroInt()
+	g.Doc.Validators = []tmtypes.GenesisValidator{}
+	for _, val := range stakingGenesis.Validators {
+		if !val.IsBonded() {
+			continue
+		}
+
+		power := val.ConsensusPower(powerReduction)
+		if power == 0 {
+			return fmt.Errorf("validator %s would be bonded with %s%s, which is below one unit of consensus power", val.OperatorAddress, val.Tokens, recipe.Denom)
+		}
+		stakingGenesis.LastValidatorPowers = append(stakingGenesis.LastValidatorPowers, stakingtypes.LastValidatorPower{
+			Address: val.OperatorAddress,
+			Power:   power,
+		})
+		stakingGenesis.LastTotalPower = stakingGenesis.LastTotalPower.Add(sdk.NewInt(power))
+
+		pubKey, err := val.ConsPubKey()
+		if err != nil {
 			return err
-		},
+		}
+		tmPubKey, err := cryptocodec.ToTmPubKeyInterface(pubKey)
+		if err != nil {
+			return err
+		}
+		g.Doc.Validators = append(g.Doc.Validators, tmtypes.GenesisValidator{
+			Address: tmPubKey.Address(),
+			PubKey:  tmPubKey,
+			Power:   power,
+			Name:    val.Description.Moniker,
+		})
 	}
 
-	return cmd
+	if err := g.SetStaking(stakingGenesis); err != nil {
+		return err
+	}
+
+	// update distribution genesis. As the staking genesis is no longer
+	// exported, the staking hooks initialize the rewards of every validator
+	// and delegation at InitChain.
+	distrGenesis, err := g.Distribution()
+	if err != nil {
+		return err
+	}
+
+	// the outstanding rewards, which include the accumulated commissions, stay
+	// in the distribution module account and go to the community pool
+	for _, rewards := range distrGenesis.OutstandingRewards {
+		distrGenesis.FeePool.CommunityPool = distrGenesis.FeePool.CommunityPool.Add(rewards.OutstandingRewards...)
+	}
+
+	distrGenesis.DelegatorStartingInfos = []distrtypes.DelegatorStartingInfoRecord{}
+	if !recipe.PreserveDelegations {
+		distrGenesis.DelegatorWithdrawInfos = []distrtypes.DelegatorWithdrawInfo{}
+	}
+	distrGenesis.OutstandingRewards = []distrtypes.ValidatorOutstandingRewardsRecord{}
+	distrGenesis.PreviousProposer = previousProposer.String()
+	distrGenesis.ValidatorAccumulatedCommissions = []distrtypes.ValidatorAccumulatedCommissionRecord{}
+	distrGenesis.ValidatorCurrentRewards = []distrtypes.ValidatorCurrentRewardsRecord{}
+	distrGenesis.ValidatorHistoricalRewards = []distrtypes.ValidatorHistoricalRewardsRecord{}
+	distrGenesis.ValidatorSlashEvents = []distrtypes.ValidatorSlashEventRecord{}
+
+	if err := g.SetDistribution(distrGenesis); err != nil {
+		return err
+	}
+
+	return applyParamOverrides(g.AppState, recipe.ParamOverrides)
+}
+
+// balanceOf returns the coins held by address, if any.
+func balanceOf(balances []banktypes.Balance, address string) sdk.Coins {
+	for _, balance := range balances {
+		if balance.Address == address {
+			return balance.Coins
+		}
+	}
+	return sdk.Coins{}
+}
+
+// addBalance adds balance.Coins to the existing balance of balance.Address or
+// appends a new balance entry.
+func addBalance(balances []banktypes.Balance, balance banktypes.Balance) []banktypes.Balance {
+	for i := range balances {
+		if balances[i].Address == balance.Address {
+			balances[i].Coins = balances[i].Coins.Add(balance.Coins...)
+			return balances
+		}
+	}
+	return banktypes.SanitizeGenesisBalances(append(balances, balance))
+}
+
+// setBalanceAmount replaces the amount of coin.Denom held by address, adding a
+// balance entry for address if needed.
+func setBalanceAmount(balances []banktypes.Balance, address string, coin sdk.Coin) []banktypes.Balance {
+	for i, balance := range balances {
+		if balance.Address != address {
+			continue
+		}
+		coins := balance.Coins.Sub(sdk.NewCoins(sdk.NewCoin(coin.Denom, balance.Coins.AmountOf(coin.Denom))))
+		balances[i].Coins = coins.Add(sdk.NewCoins(coin)...)
+		return balances
+	}
+
+	if coin.IsZero() {
+		return balances
+	}
+	return banktypes.SanitizeGenesisBalances(append(balances, banktypes.Balance{Address: address, Coins: sdk.NewCoins(coin)}))
+}
+
+// splitByWeight splits total proportionally to weights. The rounding remainder
+// is assigned to the first entry so that the parts always sum up to total.
+func splitByWeight(total sdk.Int, weights []uint64) []sdk.Int {
+	sum := sdk.ZeroInt()
+	for _, w := range weights {
+		sum = sum.Add(sdk.NewIntFromUint64(w))
+	}
+
+	parts := make([]sdk.Int, len(weights))
+	remainder := total
+	for i, w := range weights {
+		parts[i] = total.Mul(sdk.NewIntFromUint64(w)).Quo(sum)
+		remainder = remainder.Sub(parts[i])
+	}
+	parts[0] = parts[0].Add(remainder)
+
+	return parts
+}
+
+// applyParamOverrides deep merges the recipe overrides into the genesis state
+// of each module.
+func applyParamOverrides(genState map[string]json.RawMessage, overrides map[string]json.RawMessage) error {
+	for module, override := range overrides {
+		moduleState, ok := genState[module]
+		if !ok {
+			return fmt.Errorf("cannot override params of unknown module %s", module)
+		}
+
+		merged, err := mergeJSON(moduleState, override)
+		if err != nil {
+			return fmt.Errorf("failed to override %s params: %w", module, err)
+		}
+		genState[module] = merged
+	}
+
+	return nil
 }
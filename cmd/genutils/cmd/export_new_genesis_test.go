@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/go-btsg/genutils/app"
+	"github.com/go-btsg/genutils/genesis"
+)
+
+func TestSplitByWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []uint64
+		want    []int64
+	}{
+		{"single", 100, []uint64{1}, []int64{100}},
+		{"even", 100, []uint64{1, 1}, []int64{50, 50}},
+		{"remainder to first", 100, []uint64{1, 1, 1}, []int64{34, 33, 33}},
+		{"weighted", 1000, []uint64{3, 1}, []int64{750, 250}},
+		{"weighted remainder", 10, []uint64{2, 1}, []int64{7, 3}},
+		{"zero total", 0, []uint64{1, 2}, []int64{0, 0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parts := splitByWeight(sdk.NewInt(tc.total), tc.weights)
+			require.Len(t, parts, len(tc.want))
+
+			sum := sdk.ZeroInt()
+			for i, part := range parts {
+				require.Equal(t, sdk.NewInt(tc.want[i]).String(), part.String(), "part %d", i)
+				sum = sum.Add(part)
+			}
+			require.Equal(t, tc.total, sum.Int64())
+		})
+	}
+}
+
+// exportedTestGenesis returns the state of a chain with one bonded validator
+// holding 100 units of consensus power, an unbonding delegation, a
+// redelegation, and outstanding rewards in the distribution module account.
+func exportedTestGenesis(t *testing.T) *genesis.Genesis {
+	cdc := app.MakeEncodingConfig().Marshaler
+	denom := sdk.DefaultBondDenom
+
+	pubKey := ed25519.GenPrivKey().PubKey()
+	operator := sdk.ValAddress(pubKey.Address())
+	pkAny, err := codectypes.NewAnyWithValue(pubKey)
+	require.NoError(t, err)
+	tmPubKey, err := cryptocodec.ToTmPubKeyInterface(pubKey)
+	require.NoError(t, err)
+
+	bonded := sdk.NewInt(100_000_000)
+	notBonded := sdk.NewInt(5_000_000)
+	rewards := sdk.NewInt(10)
+
+	appState := app.ModuleBasics.DefaultGenesis(cdc)
+	setState := func(module string, state codec.ProtoMarshaler) {
+		bz, err := cdc.MarshalJSON(state)
+		require.NoError(t, err)
+		appState[module] = bz
+	}
+
+	validator := stakingtypes.Validator{
+		OperatorAddress:   operator.String(),
+		ConsensusPubkey:   pkAny,
+		Status:            stakingtypes.Bonded,
+		Tokens:            bonded,
+		DelegatorShares:   bonded.ToDec(),
+		Description:       stakingtypes.NewDescription("old", "", "", "", ""),
+		Commission:        stakingtypes.NewCommission(sdk.ZeroDec(), sdk.ZeroDec(), sdk.ZeroDec()),
+		MinSelfDelegation: sdk.OneInt(),
+	}
+	stakingGenesis := stakingtypes.DefaultGenesisState()
+	stakingGenesis.Exported = true
+	stakingGenesis.Validators = []stakingtypes.Validator{validator}
+	stakingGenesis.Delegations = []stakingtypes.Delegation{{
+		DelegatorAddress: sdk.AccAddress(operator).String(),
+		ValidatorAddress: operator.String(),
+		Shares:           bonded.ToDec(),
+	}}
+	stakingGenesis.UnbondingDelegations = []stakingtypes.UnbondingDelegation{
+		stakingtypes.NewUnbondingDelegation(sdk.AccAddress(operator), operator, 1, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), notBonded),
+	}
+	stakingGenesis.Redelegations = []stakingtypes.Redelegation{
+		stakingtypes.NewRedelegation(sdk.AccAddress(operator), sdk.ValAddress("other-validator-addr"), operator, 1, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), sdk.OneInt(), sdk.OneDec()),
+	}
+	stakingGenesis.LastValidatorPowers = []stakingtypes.LastValidatorPower{{Address: operator.String(), Power: 100}}
+	stakingGenesis.LastTotalPower = sdk.NewInt(100)
+	setState(stakingtypes.ModuleName, stakingGenesis)
+
+	outstanding := sdk.NewDecCoins(sdk.NewDecCoin(denom, rewards))
+	distrGenesis := distrtypes.DefaultGenesisState()
+	distrGenesis.OutstandingRewards = []distrtypes.ValidatorOutstandingRewardsRecord{
+		{ValidatorAddress: operator.String(), OutstandingRewards: outstanding},
+	}
+	distrGenesis.ValidatorAccumulatedCommissions = []distrtypes.ValidatorAccumulatedCommissionRecord{
+		{ValidatorAddress: operator.String(), Accumulated: distrtypes.ValidatorAccumulatedCommission{Commission: outstanding}},
+	}
+	distrGenesis.DelegatorStartingInfos = []distrtypes.DelegatorStartingInfoRecord{{
+		DelegatorAddress: sdk.AccAddress(operator).String(),
+		ValidatorAddress: operator.String(),
+		StartingInfo:     distrtypes.NewDelegatorStartingInfo(3, bonded.ToDec(), 10),
+	}}
+	setState(distrtypes.ModuleName, distrGenesis)
+
+	balances := []banktypes.Balance{
+		{Address: authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String(), Coins: sdk.NewCoins(sdk.NewCoin(denom, bonded))},
+		{Address: authtypes.NewModuleAddress(stakingtypes.NotBondedPoolName).String(), Coins: sdk.NewCoins(sdk.NewCoin(denom, notBonded))},
+		{Address: authtypes.NewModuleAddress(distrtypes.ModuleName).String(), Coins: sdk.NewCoins(sdk.NewCoin(denom, rewards))},
+	}
+	bankGenesis := banktypes.DefaultGenesisState()
+	bankGenesis.Balances = banktypes.SanitizeGenesisBalances(balances)
+	bankGenesis.Supply = sdk.NewCoins(sdk.NewCoin(denom, bonded.Add(notBonded).Add(rewards)))
+	setState(banktypes.ModuleName, bankGenesis)
+
+	doc := tmtypes.GenesisDoc{
+		GenesisTime:     time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		ChainID:         "test-1",
+		InitialHeight:   1,
+		ConsensusParams: tmtypes.DefaultConsensusParams(),
+		Validators: []tmtypes.GenesisValidator{
+			{Address: tmPubKey.Address(), PubKey: tmPubKey, Power: 100, Name: "old"},
+		},
+	}
+	return genesis.New(cdc, doc, appState)
+}
+
+func TestApplyForkRecipeInitChain(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+
+	tests := []struct {
+		name                string
+		preserveDelegations bool
+	}{
+		{"self delegations", false},
+		{"preserved delegations", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := exportedTestGenesis(t)
+
+			pubKey := ed25519.GenPrivKey().PubKey()
+			pubKeyJSON, err := cdc.MarshalInterfaceJSON(pubKey)
+			require.NoError(t, err)
+			operator := sdk.ValAddress(pubKey.Address())
+
+			recipe := ForkRecipe{
+				Denom: sdk.DefaultBondDenom,
+				Validators: []RecipeValidator{{
+					Owner:    sdk.AccAddress(operator).String(),
+					Operator: operator.String(),
+					PubKey:   json.RawMessage(pubKeyJSON),
+					Moniker:  "new",
+				}},
+				PreserveDelegations: tc.preserveDelegations,
+			}
+			recipe.setDefaults()
+			require.NoError(t, recipe.Validate())
+			require.NoError(t, applyForkRecipe(g, recipe))
+
+			require.Len(t, g.Doc.Validators, 1)
+			require.Equal(t, int64(100), g.Doc.Validators[0].Power)
+			require.Equal(t, pubKey.Address().Bytes(), g.Doc.Validators[0].Address.Bytes())
+
+			stakingGenesis, err := g.Staking()
+			require.NoError(t, err)
+			require.Empty(t, stakingGenesis.Redelegations)
+
+			distrGenesis, err := g.Distribution()
+			require.NoError(t, err)
+			require.Equal(t, sdk.NewDecCoins(sdk.NewInt64DecCoin(sdk.DefaultBondDenom, 10)), distrGenesis.FeePool.CommunityPool)
+			require.Empty(t, distrGenesis.DelegatorStartingInfos)
+
+			doc, err := g.GenesisDoc()
+			require.NoError(t, err)
+			validators, err := dryRunInitChain(doc, false)
+			require.NoError(t, err)
+			require.Equal(t, []initChainValidator{{
+				Operator: operator.String(),
+				Moniker:  "new",
+				ConsAddr: sdk.ConsAddress(pubKey.Address()).String(),
+				Power:    100,
+			}}, validators)
+		})
+	}
+}
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 
 	"github.com/spf13/cobra"
 
@@ -16,14 +16,17 @@ import (
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	authvesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
-	"github.com/cosmos/cosmos-sdk/x/genutil"
-	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+
+	"github.com/go-btsg/genutils/genesis"
 )
 
 const (
 	flagVestingStart = "vesting-start-time"
 	flagVestingEnd   = "vesting-end-time"
 	flagVestingAmt   = "vesting-amount"
+
+	flagVestingPeriods  = "vesting-periods"
+	flagPermanentLocked = "permanent-locked"
 )
 
 // AddGenesisAccountCmd returns add-genesis-account cobra Command.
@@ -35,6 +38,15 @@ func AddGenesisAccountCmd(defaultNodeHome string) *cobra.Command {
 the account address or key name and a list of initial coins. If a key name is given,
 the address will be looked up in the local Keybase. The list of initial tokens must
 contain valid denominations. Accounts may optionally be supplied with vesting parameters.
+
+With --vesting-periods, a periodic vesting account starting at --vesting-start-time
+is created from a YAML or JSON list of periods such as
+
+	[{"length": 2592000, "amount": "1000000ubtsg"}, {"length": 2592000, "amount": "1000000ubtsg"}]
+
+where length is in seconds. The period amounts must sum up to --vesting-amount,
+which defaults to that sum. With --permanent-locked, the vesting amount is locked
+forever and can only be delegated.
 `,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -49,140 +61,369 @@ contain valid denominations. Accounts may optionally be supplied with vesting pa
 				return fmt.Errorf("failed to parse coins: %w", err)
 			}
 
-			addr, err := sdk.AccAddressFromBech32(args[0])
+			addr, err := addressFromArg(cmd, clientCtx, args[0])
 			if err != nil {
-				inBuf := bufio.NewReader(cmd.InOrStdin())
-				keyringBackend, err := cmd.Flags().GetString(flags.FlagKeyringBackend)
-				if err != nil {
-					return err
-				}
-
-				// attempt to lookup address from Keybase if no address was provided
-				kb, err := keyring.New(sdk.KeyringServiceName(), keyringBackend, clientCtx.HomeDir, inBuf)
-				if err != nil {
-					return err
-				}
-
-				info, err := kb.Key(args[0])
-				if err != nil {
-					return fmt.Errorf("failed to get address from Keybase: %w", err)
-				}
-
-				addr = info.GetAddress()
+				return err
 			}
 
-			vestingStart, err := cmd.Flags().GetInt64(flagVestingStart)
+			vesting, err := vestingParamsFromFlags(cmd)
 			if err != nil {
 				return err
 			}
-			vestingEnd, err := cmd.Flags().GetInt64(flagVestingEnd)
+
+			genAccount, balances, err := newGenesisAccount(addr, coins, vesting.amount, vesting.start, vesting.end, vesting.periods, vesting.accountType)
 			if err != nil {
 				return err
 			}
-			vestingAmtStr, err := cmd.Flags().GetString(flagVestingAmt)
+
+			genFile := config.GenesisFile()
+			g, err := genesis.Load(clientCtx.Codec, genFile)
 			if err != nil {
+				return fmt.Errorf("failed to unmarshal genesis state: %w", err)
+			}
+			before := g.Clone()
+
+			if err := addGenesisAccounts(g, []authtypes.GenesisAccount{genAccount}, []banktypes.Balance{balances}); err != nil {
 				return err
 			}
 
-			vestingAmt, err := sdk.ParseCoinsNormalized(vestingAmtStr)
-			if err != nil {
-				return fmt.Errorf("failed to parse vesting amount: %w", err)
+			if err := fixSupply(cmd, g); err != nil {
+				return err
 			}
 
-			// create concrete account type based on input parameters
-			var genAccount authtypes.GenesisAccount
+			if err := exportGenesisFile(cmd, g, genFile); err != nil {
+				return err
+			}
 
-			balances := banktypes.Balance{Address: addr.String(), Coins: coins.Sort()}
-			baseAccount := authtypes.NewBaseAccount(addr, nil, 0, 0)
+			return writeChangeReport(cmd, before, g, genFile, genFile)
+		},
+	}
 
-			if !vestingAmt.IsZero() {
-				baseVestingAccount := authvesting.NewBaseVestingAccount(baseAccount, vestingAmt.Sort(), vestingEnd)
+	cmd.Flags().String(flags.FlagKeyringBackend, flags.DefaultKeyringBackend, "Select keyring's backend (os|file|kwallet|pass|test)")
+	cmd.Flags().String(flags.FlagHome, defaultNodeHome, "The application home directory")
+	addVestingFlags(cmd)
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+	flags.AddQueryFlagsToCmd(cmd)
 
-				if (balances.Coins.IsZero() && !baseVestingAccount.OriginalVesting.IsZero()) ||
-					baseVestingAccount.OriginalVesting.IsAnyGT(balances.Coins) {
-					return errors.New("vesting amount cannot be greater than total amount")
-				}
+	return cmd
+}
 
-				switch {
-				case vestingStart != 0 && vestingEnd != 0:
-					genAccount = authvesting.NewContinuousVestingAccountRaw(baseVestingAccount, vestingStart)
+// exportGenesisFile validates the genesis doc of g like
+// genutil.ExportGenesisFile does and writes g to genFile.
+func exportGenesisFile(cmd *cobra.Command, g *genesis.Genesis, genFile string) error {
+	if err := g.Doc.ValidateAndComplete(); err != nil {
+		return err
+	}
+	return writeGenesisFile(cmd, g, genFile)
+}
 
-				case vestingEnd != 0:
-					genAccount = authvesting.NewDelayedVestingAccountRaw(baseVestingAccount)
+// vestingParams are the vesting parameters of a genesis account command.
+type vestingParams struct {
+	amount      sdk.Coins
+	start       int64
+	end         int64
+	periods     authvesting.Periods
+	accountType string
+}
 
-				default:
-					return errors.New("invalid vesting parameters; must supply start and end time or end time")
-				}
-			} else {
-				genAccount = baseAccount
-			}
+func addVestingFlags(cmd *cobra.Command) {
+	cmd.Flags().String(flagVestingAmt, "", "amount of coins for vesting accounts")
+	cmd.Flags().Int64(flagVestingStart, 0, "schedule start time (unix epoch) for vesting accounts")
+	cmd.Flags().Int64(flagVestingEnd, 0, "schedule end time (unix epoch) for vesting accounts")
+	cmd.Flags().String(flagVestingPeriods, "", "YAML or JSON file of vesting periods for periodic vesting accounts")
+	cmd.Flags().Bool(flagPermanentLocked, false, "lock the vesting amount permanently")
+}
 
-			if err := genAccount.Validate(); err != nil {
-				return fmt.Errorf("failed to validate new genesis account: %w", err)
-			}
+// vestingParamsFromFlags reads the flags registered by addVestingFlags.
+func vestingParamsFromFlags(cmd *cobra.Command) (vestingParams, error) {
+	var vesting vestingParams
 
-			genFile := config.GenesisFile()
-			appState, genDoc, err := genutiltypes.GenesisStateFromGenFile(genFile)
-			if err != nil {
-				return fmt.Errorf("failed to unmarshal genesis state: %w", err)
-			}
+	start, err := cmd.Flags().GetInt64(flagVestingStart)
+	if err != nil {
+		return vesting, err
+	}
+	end, err := cmd.Flags().GetInt64(flagVestingEnd)
+	if err != nil {
+		return vesting, err
+	}
+	vestingAmtStr, err := cmd.Flags().GetString(flagVestingAmt)
+	if err != nil {
+		return vesting, err
+	}
 
-			authGenState := authtypes.GetGenesisStateFromAppState(clientCtx.Codec, appState)
+	vesting.start, vesting.end = start, end
+	vesting.amount, err = sdk.ParseCoinsNormalized(vestingAmtStr)
+	if err != nil {
+		return vesting, fmt.Errorf("failed to parse vesting amount: %w", err)
+	}
 
-			accs, err := authtypes.UnpackAccounts(authGenState.Accounts)
-			if err != nil {
-				return fmt.Errorf("failed to get accounts from any: %w", err)
+	periodsPath, err := cmd.Flags().GetString(flagVestingPeriods)
+	if err != nil {
+		return vesting, err
+	}
+	if periodsPath != "" {
+		if vesting.periods, err = loadVestingPeriods(periodsPath); err != nil {
+			return vesting, err
+		}
+	}
+
+	permanentLocked, err := cmd.Flags().GetBool(flagPermanentLocked)
+	if err != nil {
+		return vesting, err
+	}
+	if permanentLocked {
+		if periodsPath != "" {
+			return vesting, fmt.Errorf("--%s and --%s are mutually exclusive", flagPermanentLocked, flagVestingPeriods)
+		}
+		vesting.accountType = accountTypePermanentLocked
+	}
+
+	return vesting, nil
+}
+
+// addressFromArg parses a bech32 address, falling back to looking up a key
+// name in the local Keybase.
+func addressFromArg(cmd *cobra.Command, clientCtx client.Context, arg string) (sdk.AccAddress, error) {
+	addr, err := sdk.AccAddressFromBech32(arg)
+	if err == nil {
+		return addr, nil
+	}
+
+	inBuf := bufio.NewReader(cmd.InOrStdin())
+	keyringBackend, err := cmd.Flags().GetString(flags.FlagKeyringBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt to lookup address from Keybase if no address was provided
+	kb, err := keyring.New(sdk.KeyringServiceName(), keyringBackend, clientCtx.HomeDir, inBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := kb.Key(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address from Keybase: %w", err)
+	}
+
+	return info.GetAddress(), nil
+}
+
+const (
+	accountTypeBase            = "base"
+	accountTypeContinuous      = "continuous"
+	accountTypeDelayed         = "delayed"
+	accountTypePeriodic        = "periodic"
+	accountTypePermanentLocked = "permanent_locked"
+)
+
+// newGenesisAccount creates the concrete account type based on input
+// parameters. An empty accountType picks a base account when there is no
+// vesting amount, a periodic vesting account when periods are given, and
+// otherwise a continuous or delayed vesting account depending on whether a
+// start time is given. The vesting amount of a periodic vesting account
+// defaults to the sum of its periods.
+func newGenesisAccount(addr sdk.AccAddress, coins, vestingAmt sdk.Coins, vestingStart, vestingEnd int64, periods authvesting.Periods, accountType string) (authtypes.GenesisAccount, banktypes.Balance, error) {
+	var genAccount authtypes.GenesisAccount
+
+	balances := banktypes.Balance{Address: addr.String(), Coins: coins.Sort()}
+	baseAccount := authtypes.NewBaseAccount(addr, nil, 0, 0)
+
+	if accountType == "" {
+		switch {
+		case len(periods) > 0:
+			accountType = accountTypePeriodic
+		case vestingAmt.IsZero():
+			accountType = accountTypeBase
+		case vestingStart != 0 && vestingEnd != 0:
+			accountType = accountTypeContinuous
+		case vestingEnd != 0:
+			accountType = accountTypeDelayed
+		default:
+			return nil, balances, errors.New("invalid vesting parameters; must supply start and end time or end time")
+		}
+	}
+
+	if len(periods) > 0 && accountType != accountTypePeriodic {
+		return nil, balances, fmt.Errorf("%s account cannot have vesting periods", accountType)
+	}
+
+	if accountType == accountTypePeriodic {
+		if vestingStart == 0 {
+			return nil, balances, errors.New("periodic vesting account requires start time")
+		}
+
+		end, total, err := periodsEndAndTotal(vestingStart, periods)
+		if err != nil {
+			return nil, balances, err
+		}
+		if vestingEnd != 0 && vestingEnd != end {
+			return nil, balances, fmt.Errorf("vesting end time %d does not match the end of the vesting periods %d", vestingEnd, end)
+		}
+		if vestingAmt.IsZero() {
+			vestingAmt = total
+		} else if !vestingAmt.IsAllGTE(total) || !total.IsAllGTE(vestingAmt) {
+			return nil, balances, fmt.Errorf("vesting periods sum up to %s, not to the vesting amount %s", total, vestingAmt)
+		}
+		vestingEnd = end
+	}
+
+	if accountType != accountTypeBase {
+		if vestingAmt.IsZero() {
+			return nil, balances, fmt.Errorf("%s vesting account requires a vesting amount", accountType)
+		}
+
+		baseVestingAccount := authvesting.NewBaseVestingAccount(baseAccount, vestingAmt.Sort(), vestingEnd)
+
+		if (balances.Coins.IsZero() && !baseVestingAccount.OriginalVesting.IsZero()) ||
+			baseVestingAccount.OriginalVesting.IsAnyGT(balances.Coins) {
+			return nil, balances, errors.New("vesting amount cannot be greater than total amount")
+		}
+
+		switch accountType {
+		case accountTypeContinuous:
+			if vestingStart == 0 || vestingEnd == 0 {
+				return nil, balances, errors.New("continuous vesting account requires start and end time")
 			}
+			genAccount = authvesting.NewContinuousVestingAccountRaw(baseVestingAccount, vestingStart)
 
-			if accs.Contains(addr) {
-				return fmt.Errorf("cannot add account at existing address %s", addr)
+		case accountTypeDelayed:
+			if vestingEnd == 0 {
+				return nil, balances, errors.New("delayed vesting account requires end time")
 			}
+			genAccount = authvesting.NewDelayedVestingAccountRaw(baseVestingAccount)
 
-			// Add the new account to the set of genesis accounts and sanitize the
-			// accounts afterwards.
-			accs = append(accs, genAccount)
-			accs = authtypes.SanitizeGenesisAccounts(accs)
+		case accountTypePeriodic:
+			genAccount = authvesting.NewPeriodicVestingAccountRaw(baseVestingAccount, vestingStart, periods)
 
-			genAccs, err := authtypes.PackAccounts(accs)
-			if err != nil {
-				return fmt.Errorf("failed to convert accounts into any's: %w", err)
+		case accountTypePermanentLocked:
+			if vestingStart != 0 || vestingEnd != 0 {
+				return nil, balances, errors.New("permanent locked account cannot have start or end time")
 			}
-			authGenState.Accounts = genAccs
+			genAccount = &authvesting.PermanentLockedAccount{BaseVestingAccount: baseVestingAccount}
+
+		default:
+			return nil, balances, fmt.Errorf("unknown account type %q", accountType)
+		}
+	} else {
+		if !vestingAmt.IsZero() {
+			return nil, balances, errors.New("base account cannot have a vesting amount")
+		}
+		genAccount = baseAccount
+	}
 
-			authGenStateBz, err := clientCtx.Codec.MarshalJSON(&authGenState)
-			if err != nil {
-				return fmt.Errorf("failed to marshal auth genesis state: %w", err)
-			}
+	if err := genAccount.Validate(); err != nil {
+		return nil, balances, fmt.Errorf("failed to validate new genesis account: %w", err)
+	}
 
-			appState[authtypes.ModuleName] = authGenStateBz
+	return genAccount, balances, nil
+}
 
-			bankGenState := banktypes.GetGenesisStateFromAppState(clientCtx.Codec, appState)
-			bankGenState.Balances = append(bankGenState.Balances, balances)
-			bankGenState.Balances = banktypes.SanitizeGenesisBalances(bankGenState.Balances)
+// vestingPeriod is a period of a vesting periods file, its length being in
+// seconds.
+type vestingPeriod struct {
+	Length int64  `json:"length"`
+	Amount string `json:"amount"`
+}
 
-			bankGenStateBz, err := clientCtx.Codec.MarshalJSON(bankGenState)
-			if err != nil {
-				return fmt.Errorf("failed to marshal bank genesis state: %w", err)
-			}
+// loadVestingPeriods reads a YAML or JSON list of vesting periods.
+func loadVestingPeriods(path string) (authvesting.Periods, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vesting periods: %w", err)
+	}
 
-			appState[banktypes.ModuleName] = bankGenStateBz
+	var rawPeriods []vestingPeriod
+	if err := unmarshalYAMLOrJSON(path, bz, &rawPeriods); err != nil {
+		return nil, fmt.Errorf("failed to parse vesting periods %s: %w", path, err)
+	}
+	if len(rawPeriods) == 0 {
+		return nil, fmt.Errorf("no vesting periods in %s", path)
+	}
 
-			appStateJSON, err := json.Marshal(appState)
-			if err != nil {
-				return fmt.Errorf("failed to marshal application genesis state: %w", err)
-			}
+	return parseVestingPeriods(rawPeriods)
+}
 
-			genDoc.AppState = appStateJSON
-			return genutil.ExportGenesisFile(genDoc, genFile)
-		},
+func parseVestingPeriods(rawPeriods []vestingPeriod) (authvesting.Periods, error) {
+	periods := make(authvesting.Periods, len(rawPeriods))
+	for i, p := range rawPeriods {
+		amount, err := sdk.ParseCoinsNormalized(p.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("vesting period %d: failed to parse amount: %w", i+1, err)
+		}
+		periods[i] = authvesting.Period{Length: p.Length, Amount: amount}
 	}
+	return periods, nil
+}
 
-	cmd.Flags().String(flags.FlagKeyringBackend, flags.DefaultKeyringBackend, "Select keyring's backend (os|file|kwallet|pass|test)")
-	cmd.Flags().String(flags.FlagHome, defaultNodeHome, "The application home directory")
-	cmd.Flags().String(flagVestingAmt, "", "amount of coins for vesting accounts")
-	cmd.Flags().Int64(flagVestingStart, 0, "schedule start time (unix epoch) for vesting accounts")
-	cmd.Flags().Int64(flagVestingEnd, 0, "schedule end time (unix epoch) for vesting accounts")
-	flags.AddQueryFlagsToCmd(cmd)
+// periodsEndAndTotal returns the end time of the periods starting at start
+// and the sum of their amounts.
+func periodsEndAndTotal(start int64, periods authvesting.Periods) (int64, sdk.Coins, error) {
+	end := start
+	total := sdk.NewCoins()
+	for i, p := range periods {
+		if p.Length <= 0 {
+			return 0, nil, fmt.Errorf("vesting period %d: length must be positive", i+1)
+		}
+		if p.Amount.IsZero() {
+			return 0, nil, fmt.Errorf("vesting period %d: amount must be positive", i+1)
+		}
+		end += p.Length
+		total = total.Add(p.Amount...)
+	}
+	return end, total, nil
+}
 
-	return cmd
+// addGenesisAccounts adds the accounts and their balances to the auth and bank
+// genesis states of g. It fails if any of the accounts already exists.
+func addGenesisAccounts(g *genesis.Genesis, genAccounts []authtypes.GenesisAccount, balances []banktypes.Balance) error {
+	accs, err := g.Accounts()
+	if err != nil {
+		return err
+	}
+
+	existing := genesisAccountSet(accs)
+	for _, genAccount := range genAccounts {
+		addr := genAccount.GetAddress()
+		if _, ok := existing[string(addr)]; ok {
+			return fmt.Errorf("cannot add account at existing address %s", addr)
+		}
+		existing[string(addr)] = struct{}{}
+
+		// Add the new account to the set of genesis accounts
+		accs = append(accs, genAccount)
+	}
+
+	// sanitize the accounts afterwards
+	if err := g.SetAccounts(authtypes.SanitizeGenesisAccounts(accs)); err != nil {
+		return err
+	}
+
+	bankGenState, err := g.Bank()
+	if err != nil {
+		return err
+	}
+	added := sdk.NewCoins()
+	for _, balance := range balances {
+		added = added.Add(balance.Coins...)
+	}
+	if err := adjustSupply(&bankGenState, added, nil); err != nil {
+		return err
+	}
+	bankGenState.Balances = append(bankGenState.Balances, balances...)
+	bankGenState.Balances = banktypes.SanitizeGenesisBalances(bankGenState.Balances)
+
+	return g.SetBank(bankGenState)
+}
+
+// genesisAccountSet returns the addresses of accs, as raw bytes, for constant
+// time lookups. GenesisAccounts.Contains decodes every address on each call.
+func genesisAccountSet(accs authtypes.GenesisAccounts) map[string]struct{} {
+	set := make(map[string]struct{}, len(accs))
+	for _, acc := range accs {
+		set[string(acc.GetAddress())] = struct{}{}
+	}
+	return set
 }
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	authvesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+// genesisAccountRow is a single account of an add-genesis-accounts-bulk file.
+type genesisAccountRow struct {
+	Address       string `json:"address"`
+	Coins         string `json:"coins"`
+	VestingAmount string `json:"vesting_amount"`
+	VestingStart  int64  `json:"vesting_start"`
+	VestingEnd    int64  `json:"vesting_end"`
+	Type          string `json:"type"`
+
+	// Periods can only be given in YAML or JSON files.
+	Periods []vestingPeriod `json:"periods,omitempty"`
+}
+
+// AddGenesisAccountsBulkCmd returns add-genesis-accounts-bulk cobra Command.
+func AddGenesisAccountsBulkCmd(defaultNodeHome string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-genesis-accounts-bulk [file]",
+		Short: "Add many genesis accounts to genesis.json at once",
+		Long: `Add many genesis accounts to genesis.json at once. The accounts are read from
+a CSV file with the columns
+
+	address,coins,vesting_amount,vesting_start,vesting_end,type
+
+and an optional header line, or from a YAML/JSON list of objects with the same
+fields. The type is one of base, continuous, delayed, periodic or
+permanent_locked and may be left empty to infer it from the vesting parameters
+like add-genesis-account does. The periods of periodic vesting accounts are
+given as a list of length/amount objects and are only supported in YAML or
+JSON files.
+
+Every row is validated before the genesis file is touched and all the invalid
+rows are reported at once. The genesis file is then written a single time.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+			serverCtx := server.GetServerContextFromCmd(cmd)
+			config := serverCtx.Config
+
+			config.SetRoot(clientCtx.HomeDir)
+
+			rows, err := loadGenesisAccountRows(args[0])
+			if err != nil {
+				return err
+			}
+
+			genFile := config.GenesisFile()
+			g, err := genesis.Load(clientCtx.Codec, genFile)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal genesis state: %w", err)
+			}
+			before := g.Clone()
+
+			existing, err := g.Accounts()
+			if err != nil {
+				return err
+			}
+
+			genAccounts, balances, err := buildGenesisAccountRows(rows, existing)
+			if err != nil {
+				return err
+			}
+
+			if err := addGenesisAccounts(g, genAccounts, balances); err != nil {
+				return err
+			}
+
+			if err := fixSupply(cmd, g); err != nil {
+				return err
+			}
+
+			if err := exportGenesisFile(cmd, g, genFile); err != nil {
+				return err
+			}
+
+			if err := writeChangeReport(cmd, before, g, genFile, genFile); err != nil {
+				return err
+			}
+
+			cmd.Printf("added %d genesis accounts\n", len(genAccounts))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flags.FlagHome, defaultNodeHome, "The application home directory")
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// loadGenesisAccountRows reads the rows of a CSV, YAML or JSON accounts file.
+func loadGenesisAccountRows(path string) ([]genesisAccountRow, error) {
+	var rows []genesisAccountRow
+
+	if strings.ToLower(filepath.Ext(path)) != ".csv" {
+		bz, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read accounts: %w", err)
+		}
+		if err := unmarshalYAMLOrJSON(path, bz, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse accounts %s: %w", path, err)
+		}
+		return rows, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounts: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse accounts %s: %w", path, err)
+	}
+
+	var rowErrs []string
+	for i, record := range records {
+		// skip an optional header line
+		if i == 0 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "address") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 6 {
+			rowErrs = append(rowErrs, fmt.Sprintf("line %d: expected between 2 and 6 columns, got %d", i+1, len(record)))
+			continue
+		}
+
+		field := func(n int) string {
+			if n < len(record) {
+				return strings.TrimSpace(record[n])
+			}
+			return ""
+		}
+
+		row := genesisAccountRow{
+			Address:       field(0),
+			Coins:         field(1),
+			VestingAmount: field(2),
+			Type:          field(5),
+		}
+		if row.VestingStart, err = parseOptionalInt64(field(3)); err != nil {
+			rowErrs = append(rowErrs, fmt.Sprintf("line %d: invalid vesting start: %s", i+1, err))
+			continue
+		}
+		if row.VestingEnd, err = parseOptionalInt64(field(4)); err != nil {
+			rowErrs = append(rowErrs, fmt.Sprintf("line %d: invalid vesting end: %s", i+1, err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rowErrs) > 0 {
+		return nil, fmt.Errorf("%d invalid rows in %s:\n%s", len(rowErrs), path, strings.Join(rowErrs, "\n"))
+	}
+	return rows, nil
+}
+
+// buildGenesisAccountRows builds the account and balance of every row. All
+// rows are validated, including against the existing accounts, before an
+// error listing every invalid row is returned.
+func buildGenesisAccountRows(rows []genesisAccountRow, existing authtypes.GenesisAccounts) ([]authtypes.GenesisAccount, []banktypes.Balance, error) {
+	genAccounts := make([]authtypes.GenesisAccount, 0, len(rows))
+	balances := make([]banktypes.Balance, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+	existingAddrs := genesisAccountSet(existing)
+
+	var rowErrs []string
+	for i, row := range rows {
+		genAccount, balance, err := row.build()
+		if err != nil {
+			rowErrs = append(rowErrs, fmt.Sprintf("row %d (%s): %s", i+1, row.Address, err))
+			continue
+		}
+
+		if prev, ok := seen[row.Address]; ok {
+			rowErrs = append(rowErrs, fmt.Sprintf("row %d (%s): duplicate of row %d", i+1, row.Address, prev))
+			continue
+		}
+		seen[row.Address] = i + 1
+
+		if _, ok := existingAddrs[string(genAccount.GetAddress())]; ok {
+			rowErrs = append(rowErrs, fmt.Sprintf("row %d (%s): account already exists in genesis", i+1, row.Address))
+			continue
+		}
+
+		genAccounts = append(genAccounts, genAccount)
+		balances = append(balances, balance)
+	}
+
+	if len(rowErrs) > 0 {
+		return nil, nil, fmt.Errorf("%d invalid rows:\n%s", len(rowErrs), strings.Join(rowErrs, "\n"))
+	}
+	return genAccounts, balances, nil
+}
+
+func (row genesisAccountRow) build() (authtypes.GenesisAccount, banktypes.Balance, error) {
+	addr, err := sdk.AccAddressFromBech32(row.Address)
+	if err != nil {
+		return nil, banktypes.Balance{}, fmt.Errorf("invalid address: %w", err)
+	}
+
+	coins, err := sdk.ParseCoinsNormalized(row.Coins)
+	if err != nil {
+		return nil, banktypes.Balance{}, fmt.Errorf("failed to parse coins: %w", err)
+	}
+
+	vestingAmt, err := sdk.ParseCoinsNormalized(row.VestingAmount)
+	if err != nil {
+		return nil, banktypes.Balance{}, fmt.Errorf("failed to parse vesting amount: %w", err)
+	}
+
+	var periods authvesting.Periods
+	if len(row.Periods) > 0 {
+		if periods, err = parseVestingPeriods(row.Periods); err != nil {
+			return nil, banktypes.Balance{}, err
+		}
+	}
+
+	return newGenesisAccount(addr, coins, vestingAmt, row.VestingStart, row.VestingEnd, periods, strings.ToLower(row.Type))
+}
+
+func parseOptionalInt64(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const flagToCommunityPool = "to-community-pool"
+
+// RemoveGenesisAccountCmd returns remove-genesis-account cobra Command.
+func RemoveGenesisAccountCmd(defaultNodeHome string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-genesis-account [address_or_key_name]",
+		Short: "Remove a genesis account from genesis.json",
+		Long: `Remove a genesis account and its balance from genesis.json. The removed coins
+are burned, i.e. subtracted from the total supply, unless --to-community-pool
+is given, in which case they are added to the community pool. Module accounts
+cannot be removed.
+
+The staking state of the account is left untouched.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+			serverCtx := server.GetServerContextFromCmd(cmd)
+			config := serverCtx.Config
+
+			config.SetRoot(clientCtx.HomeDir)
+
+			addr, err := addressFromArg(cmd, clientCtx, args[0])
+			if err != nil {
+				return err
+			}
+
+			toCommunityPool, err := cmd.Flags().GetBool(flagToCommunityPool)
+			if err != nil {
+				return err
+			}
+
+			genFile := config.GenesisFile()
+			g, err := genesis.Load(clientCtx.Codec, genFile)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal genesis state: %w", err)
+			}
+			before := g.Clone()
+
+			removed, err := removeGenesisAccount(g, addr, toCommunityPool)
+			if err != nil {
+				return err
+			}
+
+			if err := fixSupply(cmd, g); err != nil {
+				return err
+			}
+
+			if n := countDelegations(g, addr); n > 0 {
+				cmd.Printf("warning: %s still has %d delegations\n", addr, n)
+			}
+
+			if err := exportGenesisFile(cmd, g, genFile); err != nil {
+				return err
+			}
+
+			if err := writeChangeReport(cmd, before, g, genFile, genFile); err != nil {
+				return err
+			}
+
+			if toCommunityPool {
+				cmd.Printf("removed %s, moved %s to the community pool\n", addr, removed)
+			} else {
+				cmd.Printf("removed %s, burned %s\n", addr, removed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flags.FlagKeyringBackend, flags.DefaultKeyringBackend, "Select keyring's backend (os|file|kwallet|pass|test)")
+	cmd.Flags().String(flags.FlagHome, defaultNodeHome, "The application home directory")
+	cmd.Flags().Bool(flagToCommunityPool, false, "Move the removed coins to the community pool instead of burning them")
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// UpdateGenesisAccountCmd returns update-genesis-account cobra Command.
+func UpdateGenesisAccountCmd(defaultNodeHome string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update-genesis-account [address_or_key_name] [coin][,[coin]]",
+		Short: "Update the balance or vesting schedule of a genesis account",
+		Long: `Set the balance of an existing genesis account to the given coins. The total
+supply follows the change of balance and is then recomputed from the balances
+unless --no-supply-fix is given.
+With --to-community-pool, coins removed from the balance are added to the
+community pool instead of being burned.
+
+When any of the vesting flags is given, the account is replaced by a new one
+built like add-genesis-account does, keeping its account number, sequence and
+public key. This can be used to convert a base account into a vesting account
+or the other way around. Otherwise the account itself is left as is. Module
+accounts cannot be updated.
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+			serverCtx := server.GetServerContextFromCmd(cmd)
+			config := serverCtx.Config
+
+			config.SetRoot(clientCtx.HomeDir)
+
+			addr, err := addressFromArg(cmd, clientCtx, args[0])
+			if err != nil {
+				return err
+			}
+
+			coins, err := sdk.ParseCoinsNormalized(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to parse coins: %w", err)
+			}
+
+			var vesting *vestingParams
+			for _, name := range []string{flagVestingAmt, flagVestingStart, flagVestingEnd, flagVestingPeriods, flagPermanentLocked} {
+				if cmd.Flags().Changed(name) {
+					params, err := vestingParamsFromFlags(cmd)
+					if err != nil {
+						return err
+					}
+					vesting = &params
+					break
+				}
+			}
+
+			toCommunityPool, err := cmd.Flags().GetBool(flagToCommunityPool)
+			if err != nil {
+				return err
+			}
+
+			genFile := config.GenesisFile()
+			g, err := genesis.Load(clientCtx.Codec, genFile)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal genesis state: %w", err)
+			}
+			before := g.Clone()
+
+			if err := updateGenesisAccount(g, addr, coins, vesting, toCommunityPool); err != nil {
+				return err
+			}
+
+			if err := fixSupply(cmd, g); err != nil {
+				return err
+			}
+
+			if err := exportGenesisFile(cmd, g, genFile); err != nil {
+				return err
+			}
+
+			return writeChangeReport(cmd, before, g, genFile, genFile)
+		},
+	}
+
+	cmd.Flags().String(flags.FlagKeyringBackend, flags.DefaultKeyringBackend, "Select keyring's backend (os|file|kwallet|pass|test)")
+	cmd.Flags().String(flags.FlagHome, defaultNodeHome, "The application home directory")
+	cmd.Flags().Bool(flagToCommunityPool, false, "Move coins removed from the balance to the community pool instead of burning them")
+	addVestingFlags(cmd)
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// findGenesisAccount returns the unpacked auth genesis accounts of g and the
+// index of addr among them. Module accounts are refused.
+func findGenesisAccount(g *genesis.Genesis, addr sdk.AccAddress) (authtypes.GenesisAccounts, int, error) {
+	accs, err := g.Accounts()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, acc := range accs {
+		if !acc.GetAddress().Equals(addr) {
+			continue
+		}
+		if macc, ok := acc.(authtypes.ModuleAccountI); ok {
+			return nil, 0, fmt.Errorf("%s is the %s module account", addr, macc.GetName())
+		}
+		return accs, i, nil
+	}
+
+	return nil, 0, fmt.Errorf("account %s not found in genesis", addr)
+}
+
+// removeGenesisAccount removes the account and the balance of addr and returns
+// the removed coins.
+func removeGenesisAccount(g *genesis.Genesis, addr sdk.AccAddress, toCommunityPool bool) (sdk.Coins, error) {
+	accs, i, err := findGenesisAccount(g, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	accs = append(accs[:i], accs[i+1:]...)
+	if err := g.SetAccounts(authtypes.SanitizeGenesisAccounts(accs)); err != nil {
+		return nil, err
+	}
+
+	bankGenState, err := g.Bank()
+	if err != nil {
+		return nil, err
+	}
+	removed := balanceOf(bankGenState.Balances, addr.String())
+	bankGenState.Balances = setBalance(bankGenState.Balances, addr.String(), sdk.Coins{})
+
+	if err := moveRemovedCoins(g, bankGenState, sdk.Coins{}, removed, toCommunityPool); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// updateGenesisAccount sets the balance of addr to coins and, if vesting is
+// not nil, replaces its account with a new one built from the vesting
+// parameters.
+func updateGenesisAccount(g *genesis.Genesis, addr sdk.AccAddress, coins sdk.Coins, vesting *vestingParams, toCommunityPool bool) error {
+	accs, i, err := findGenesisAccount(g, addr)
+	if err != nil {
+		return err
+	}
+
+	if vesting != nil {
+		genAccount, _, err := newGenesisAccount(addr, coins, vesting.amount, vesting.start, vesting.end, vesting.periods, vesting.accountType)
+		if err != nil {
+			return err
+		}
+
+		old := accs[i]
+		if err := genAccount.SetAccountNumber(old.GetAccountNumber()); err != nil {
+			return err
+		}
+		if err := genAccount.SetSequence(old.GetSequence()); err != nil {
+			return err
+		}
+		if err := genAccount.SetPubKey(old.GetPubKey()); err != nil {
+			return err
+		}
+		accs[i] = genAccount
+	}
+
+	if err := g.SetAccounts(authtypes.SanitizeGenesisAccounts(accs)); err != nil {
+		return err
+	}
+
+	bankGenState, err := g.Bank()
+	if err != nil {
+		return err
+	}
+	old := balanceOf(bankGenState.Balances, addr.String())
+	bankGenState.Balances = setBalance(bankGenState.Balances, addr.String(), coins)
+
+	added, removed := sdk.NewCoins(), sdk.NewCoins()
+	for _, denom := range unionDenoms(old, coins) {
+		diff := coins.AmountOf(denom).Sub(old.AmountOf(denom))
+		switch {
+		case diff.IsPositive():
+			added = added.Add(sdk.NewCoin(denom, diff))
+		case diff.IsNegative():
+			removed = removed.Add(sdk.NewCoin(denom, diff.Neg()))
+		}
+	}
+
+	return moveRemovedCoins(g, bankGenState, added, removed, toCommunityPool)
+}
+
+// moveRemovedCoins adds the added coins to the supply and either burns the
+// removed ones or moves them to the community pool, then stores bankGenState
+// into g.
+func moveRemovedCoins(g *genesis.Genesis, bankGenState banktypes.GenesisState, added, removed sdk.Coins, toCommunityPool bool) error {
+	if toCommunityPool && !removed.IsZero() {
+		distrGenState, err := g.Distribution()
+		if err != nil {
+			return err
+		}
+
+		distrGenState.FeePool.CommunityPool = distrGenState.FeePool.CommunityPool.Add(sdk.NewDecCoinsFromCoins(removed...)...)
+		bankGenState.Balances = addBalance(bankGenState.Balances, banktypes.Balance{
+			Address: authtypes.NewModuleAddress(distrtypes.ModuleName).String(),
+			Coins:   removed,
+		})
+
+		if err := g.SetDistribution(distrGenState); err != nil {
+			return err
+		}
+		removed = sdk.Coins{}
+	}
+
+	if err := adjustSupply(&bankGenState, added, removed); err != nil {
+		return err
+	}
+
+	return g.SetBank(bankGenState)
+}
+
+// setBalance replaces the balance of address, removing its entry when coins
+// is empty.
+func setBalance(balances []banktypes.Balance, address string, coins sdk.Coins) []banktypes.Balance {
+	for i, balance := range balances {
+		if balance.Address != address {
+			continue
+		}
+		if coins.Empty() {
+			return append(balances[:i], balances[i+1:]...)
+		}
+		balances[i].Coins = coins
+		return balances
+	}
+
+	if coins.Empty() {
+		return balances
+	}
+	return banktypes.SanitizeGenesisBalances(append(balances, banktypes.Balance{Address: address, Coins: coins}))
+}
+
+// countDelegations returns the number of delegations of addr.
+func countDelegations(g *genesis.Genesis, addr sdk.AccAddress) int {
+	stakingGenState, err := g.Staking()
+	if err != nil {
+		return 0
+	}
+
+	n := 0
+	for _, del := range stakingGenState.Delegations {
+		if del.DelegatorAddress == addr.String() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const flagOutput = "output"
+
+// InvariantViolation is a broken invariant found in a genesis state.
+type InvariantViolation struct {
+	Module    string `json:"module"`
+	Invariant string `json:"invariant"`
+	Message   string `json:"message"`
+}
+
+func (v InvariantViolation) String() string {
+	return fmt.Sprintf("%s/%s: %s", v.Module, v.Invariant, v.Message)
+}
+
+// CheckInvariantsCmd returns check-invariants cobra Command.
+func CheckInvariantsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-invariants [genesis-file]",
+		Short: "Check the crisis invariants of a genesis file",
+		Long: `Check that a genesis file is internally consistent by evaluating the bank,
+staking, distribution and gov crisis invariants over its app state. Every
+violation is reported together with the offending addresses and amounts.
+
+Invariants that only hold once the state has been initialized by the chain,
+such as the distribution reference counts, are only checked for exported
+staking states.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+
+			violations, err := checkGenesisInvariants(g)
+			if err != nil {
+				return err
+			}
+
+			output, err := cmd.Flags().GetString(flagOutput)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				bz, err := json.MarshalIndent(violations, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(bz))
+			default:
+				for _, v := range violations {
+					fmt.Fprintln(cmd.OutOrStdout(), v.String())
+				}
+			}
+
+			if len(violations) > 0 {
+				return fmt.Errorf("found %d invariant violations", len(violations))
+			}
+			if output != "json" {
+				fmt.Fprintln(cmd.OutOrStdout(), "all invariants hold")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP(flagOutput, "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+// checkGenesisInvariants evaluates the bank, staking, distribution and gov
+// invariants over g and returns every violation found.
+func checkGenesisInvariants(g *genesis.Genesis) ([]InvariantViolation, error) {
+	bankGenesis, err := g.Bank()
+	if err != nil {
+		return nil, err
+	}
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return nil, err
+	}
+	distrGenesis, err := g.Distribution()
+	if err != nil {
+		return nil, err
+	}
+	govGenesis, err := g.Gov()
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]sdk.Coins, len(bankGenesis.Balances))
+	for _, balance := range bankGenesis.Balances {
+		// invalid balances are reported by the bank invariants
+		if balance.Coins.Validate() != nil {
+			continue
+		}
+		balances[balance.Address] = balances[balance.Address].Add(balance.Coins...)
+	}
+
+	var violations []InvariantViolation
+	violations = append(violations, bankInvariants(bankGenesis)...)
+	violations = append(violations, stakingInvariants(stakingGenesis, balances)...)
+	violations = append(violations, distributionInvariants(distrGenesis, stakingGenesis, balances)...)
+	violations = append(violations, govInvariants(govGenesis, balances)...)
+
+	return violations, nil
+}
+
+func bankInvariants(bankGenesis banktypes.GenesisState) []InvariantViolation {
+	var violations []InvariantViolation
+
+	seen := make(map[string]bool, len(bankGenesis.Balances))
+	total := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		if seen[balance.Address] {
+			violations = append(violations, InvariantViolation{
+				Module: banktypes.ModuleName, Invariant: "duplicate-balance",
+				Message: fmt.Sprintf("%s has more than one balance entry", balance.Address),
+			})
+		}
+		seen[balance.Address] = true
+
+		if err := balance.Coins.Validate(); err != nil {
+			violations = append(violations, InvariantViolation{
+				Module: banktypes.ModuleName, Invariant: "valid-balances",
+				Message: fmt.Sprintf("%s has invalid balance %s: %s", balance.Address, balance.Coins, err),
+			})
+			continue
+		}
+		total = total.Add(balance.Coins...)
+	}
+
+	// a genesis without supply lets the chain compute it, as InitGenesis does
+	if bankGenesis.Supply.Empty() {
+		return violations
+	}
+	if err := bankGenesis.Supply.Validate(); err != nil {
+		return append(violations, InvariantViolation{
+			Module: banktypes.ModuleName, Invariant: "valid-supply",
+			Message: fmt.Sprintf("invalid supply %s: %s", bankGenesis.Supply, err),
+		})
+	}
+
+	for _, denom := range unionDenoms(total, bankGenesis.Supply) {
+		if !total.AmountOf(denom).Equal(bankGenesis.Supply.AmountOf(denom)) {
+			violations = append(violations, InvariantViolation{
+				Module: banktypes.ModuleName, Invariant: "total-supply",
+				Message: fmt.Sprintf("supply of %s is %s but balances sum up to %s",
+					denom, bankGenesis.Supply.AmountOf(denom), total.AmountOf(denom)),
+			})
+		}
+	}
+
+	return violations
+}
+
+func stakingInvariants(stakingGenesis stakingtypes.GenesisState, balances map[string]sdk.Coins) []InvariantViolation {
+	var violations []InvariantViolation
+	bondDenom := stakingGenesis.Params.BondDenom
+
+	// module-accounts
+	bonded := sdk.ZeroInt()
+	notBonded := sdk.ZeroInt()
+	validators := make(map[string]stakingtypes.Validator, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = val
+		switch val.GetStatus() {
+		case stakingtypes.Bonded:
+			bonded = bonded.Add(val.Tokens)
+		case stakingtypes.Unbonding, stakingtypes.Unbonded:
+			notBonded = notBonded.Add(val.Tokens)
+		default:
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "validator-status",
+				Message: fmt.Sprintf("validator %s has invalid status %s", val.OperatorAddress, val.Status),
+			})
+		}
+	}
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		for _, entry := range ubd.Entries {
+			notBonded = notBonded.Add(entry.Balance)
+		}
+	}
+
+	bondedPool := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+	notBondedPool := authtypes.NewModuleAddress(stakingtypes.NotBondedPoolName).String()
+	if amt := balances[bondedPool].AmountOf(bondDenom); !amt.Equal(bonded) {
+		violations = append(violations, InvariantViolation{
+			Module: stakingtypes.ModuleName, Invariant: "module-accounts",
+			Message: fmt.Sprintf("bonded pool %s holds %s%s but bonded validators hold %s%s",
+				bondedPool, amt, bondDenom, bonded, bondDenom),
+		})
+	}
+	if amt := balances[notBondedPool].AmountOf(bondDenom); !amt.Equal(notBonded) {
+		violations = append(violations, InvariantViolation{
+			Module: stakingtypes.ModuleName, Invariant: "module-accounts",
+			Message: fmt.Sprintf("not bonded pool %s holds %s%s but unbonded validators and unbonding delegations hold %s%s",
+				notBondedPool, amt, bondDenom, notBonded, bondDenom),
+		})
+	}
+
+	// positive-delegation and delegator-shares
+	shares := make(map[string]sdk.Dec, len(validators))
+	for _, del := range stakingGenesis.Delegations {
+		if !del.Shares.IsPositive() {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "positive-delegation",
+				Message: fmt.Sprintf("delegation of %s to %s has non-positive shares %s",
+					del.DelegatorAddress, del.ValidatorAddress, del.Shares),
+			})
+		}
+		if _, ok := validators[del.ValidatorAddress]; !ok {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "delegator-shares",
+				Message: fmt.Sprintf("delegation of %s references unknown validator %s",
+					del.DelegatorAddress, del.ValidatorAddress),
+			})
+			continue
+		}
+		if total, ok := shares[del.ValidatorAddress]; ok {
+			shares[del.ValidatorAddress] = total.Add(del.Shares)
+		} else {
+			shares[del.ValidatorAddress] = del.Shares
+		}
+	}
+	for _, val := range stakingGenesis.Validators {
+		total, ok := shares[val.OperatorAddress]
+		if !ok {
+			total = sdk.ZeroDec()
+		}
+		if !total.Equal(val.DelegatorShares) {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "delegator-shares",
+				Message: fmt.Sprintf("validator %s has %s delegator shares but its delegations sum up to %s",
+					val.OperatorAddress, val.DelegatorShares, total),
+			})
+		}
+		if val.Tokens.IsNegative() {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "nonnegative-power",
+				Message: fmt.Sprintf("validator %s has negative tokens %s", val.OperatorAddress, val.Tokens),
+			})
+		}
+	}
+
+	if !stakingGenesis.Exported {
+		return violations
+	}
+
+	// nonnegative-power over the exported last validator powers
+	totalPower := sdk.ZeroInt()
+	for _, lvp := range stakingGenesis.LastValidatorPowers {
+		totalPower = totalPower.Add(sdk.NewInt(lvp.Power))
+		val, ok := validators[lvp.Address]
+		if !ok {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "nonnegative-power",
+				Message: fmt.Sprintf("last validator power references unknown validator %s", lvp.Address),
+			})
+			continue
+		}
+		if lvp.Power < 0 || !val.IsBonded() {
+			violations = append(violations, InvariantViolation{
+				Module: stakingtypes.ModuleName, Invariant: "nonnegative-power",
+				Message: fmt.Sprintf("validator %s has last power %d with status %s", lvp.Address, lvp.Power, val.Status),
+			})
+		}
+	}
+	if !totalPower.Equal(stakingGenesis.LastTotalPower) {
+		violations = append(violations, InvariantViolation{
+			Module: stakingtypes.ModuleName, Invariant: "nonnegative-power",
+			Message: fmt.Sprintf("last total power is %s but last validator powers sum up to %s",
+				stakingGenesis.LastTotalPower, totalPower),
+		})
+	}
+
+	return violations
+}
+
+func distributionInvariants(distrGenesis distrtypes.GenesisState, stakingGenesis stakingtypes.GenesisState, balances map[string]sdk.Coins) []InvariantViolation {
+	var violations []InvariantViolation
+
+	// nonnegative-outstanding
+	var outstanding sdk.DecCoins
+	for _, rewards := range distrGenesis.OutstandingRewards {
+		if rewards.OutstandingRewards.IsAnyNegative() {
+			violations = append(violations, InvariantViolation{
+				Module: distrtypes.ModuleName, Invariant: "nonnegative-outstanding",
+				Message: fmt.Sprintf("validator %s has negative outstanding rewards %s",
+					rewards.ValidatorAddress, rewards.OutstandingRewards),
+			})
+			// left out of the module account check, which cannot truncate
+			// negative amounts
+			continue
+		}
+		outstanding = outstanding.Add(rewards.OutstandingRewards...)
+	}
+
+	// module-account
+	expected, _ := outstanding.Add(distrGenesis.FeePool.CommunityPool...).TruncateDecimal()
+	distrAddr := authtypes.NewModuleAddress(distrtypes.ModuleName).String()
+	if !coinsEqual(balances[distrAddr], expected) {
+		violations = append(violations, InvariantViolation{
+			Module: distrtypes.ModuleName, Invariant: "module-account",
+			Message: fmt.Sprintf("distribution module account %s holds %s but outstanding rewards and community pool sum up to %s",
+				distrAddr, balances[distrAddr], expected),
+		})
+	}
+
+	if !stakingGenesis.Exported {
+		return violations
+	}
+
+	// reference-count
+	expectedRefs := uint64(len(stakingGenesis.Validators) + len(stakingGenesis.Delegations) + len(distrGenesis.ValidatorSlashEvents))
+	var refs uint64
+	for _, hist := range distrGenesis.ValidatorHistoricalRewards {
+		refs += uint64(hist.Rewards.ReferenceCount)
+	}
+	if refs != expectedRefs {
+		violations = append(violations, InvariantViolation{
+			Module: distrtypes.ModuleName, Invariant: "reference-count",
+			Message: fmt.Sprintf("historical rewards hold %d references but validators, delegations and slash events require %d",
+				refs, expectedRefs),
+		})
+	}
+
+	return violations
+}
+
+func govInvariants(govGenesis govtypes.GenesisState, balances map[string]sdk.Coins) []InvariantViolation {
+	var deposits sdk.Coins
+	for _, deposit := range govGenesis.Deposits {
+		deposits = deposits.Add(deposit.Amount...)
+	}
+
+	govAddr := authtypes.NewModuleAddress(govtypes.ModuleName).String()
+	if !coinsEqual(balances[govAddr], deposits) {
+		return []InvariantViolation{{
+			Module: govtypes.ModuleName, Invariant: "deposits",
+			Message: fmt.Sprintf("gov module account %s holds %s but deposits sum up to %s",
+				govAddr, balances[govAddr], deposits),
+		}}
+	}
+
+	return nil
+}
+
+// unionDenoms returns the sorted denoms present in any of the given coins.
+func unionDenoms(coins ...sdk.Coins) []string {
+	seen := make(map[string]bool)
+	var denoms []string
+	for _, c := range coins {
+		for _, coin := range c {
+			if !seen[coin.Denom] {
+				seen[coin.Denom] = true
+				denoms = append(denoms, coin.Denom)
+			}
+		}
+	}
+	sort.Strings(denoms)
+	return denoms
+}
+
+// coinsEqual reports whether a and b hold the same amount of every denom.
+// Unlike sdk.Coins.IsEqual and sdk.Coins.AmountOf, it does not panic when the
+// denoms differ or are invalid, nor require the coins to be sorted.
+func coinsEqual(a, b sdk.Coins) bool {
+	amounts := make(map[string]sdk.Int)
+	for _, coin := range a {
+		if amt, ok := amounts[coin.Denom]; ok {
+			amounts[coin.Denom] = amt.Add(coin.Amount)
+		} else {
+			amounts[coin.Denom] = coin.Amount
+		}
+	}
+	for _, coin := range b {
+		if amt, ok := amounts[coin.Denom]; ok {
+			amounts[coin.Denom] = amt.Sub(coin.Amount)
+		} else {
+			amounts[coin.Denom] = coin.Amount.Neg()
+		}
+	}
+	for _, amt := range amounts {
+		if !amt.IsZero() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBankInvariants(t *testing.T) {
+	invalid := sdk.Coins{{Denom: "1invalid", Amount: sdk.NewInt(5)}}
+
+	tests := []struct {
+		name           string
+		balances       []banktypes.Balance
+		supply         sdk.Coins
+		wantInvariants []string
+	}{
+		{
+			name:     "matching supply",
+			balances: []banktypes.Balance{{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))}},
+			supply:   sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)),
+		},
+		{
+			name:     "no supply",
+			balances: []banktypes.Balance{{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))}},
+		},
+		{
+			name: "duplicate balance",
+			balances: []banktypes.Balance{
+				{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))},
+				{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))},
+			},
+			supply:         sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 20)),
+			wantInvariants: []string{"duplicate-balance"},
+		},
+		{
+			name:           "different supply",
+			balances:       []banktypes.Balance{{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))}},
+			supply:         sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 11)),
+			wantInvariants: []string{"total-supply"},
+		},
+		{
+			name:           "invalid balance",
+			balances:       []banktypes.Balance{{Address: "addr1", Coins: invalid}},
+			supply:         sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)),
+			wantInvariants: []string{"valid-balances", "total-supply"},
+		},
+		{
+			name:           "invalid supply",
+			balances:       []banktypes.Balance{{Address: "addr1", Coins: sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10))}},
+			supply:         invalid,
+			wantInvariants: []string{"valid-supply"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := bankInvariants(banktypes.GenesisState{Balances: tc.balances, Supply: tc.supply})
+			var invariants []string
+			for _, v := range violations {
+				invariants = append(invariants, v.Invariant)
+			}
+			require.Equal(t, tc.wantInvariants, invariants)
+		})
+	}
+}
+
+func TestCoinsEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b sdk.Coins
+		want bool
+	}{
+		{"equal", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), true},
+		{"both empty", nil, sdk.Coins{}, true},
+		{"zero amount", sdk.Coins{sdk.NewInt64Coin("ubtsg", 0)}, nil, true},
+		{"unsorted", sdk.Coins{sdk.NewInt64Coin("uother", 1), sdk.NewInt64Coin("ubtsg", 10)},
+			sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10), sdk.NewInt64Coin("uother", 1)), true},
+		{"different denom", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("uother", 10)), false},
+		{"invalid denom", sdk.Coins{{Denom: "1invalid", Amount: sdk.NewInt(10)}}, sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, coinsEqual(tc.a, tc.b))
+			require.Equal(t, tc.want, coinsEqual(tc.b, tc.a))
+		})
+	}
+}
+
+func TestGovInvariants(t *testing.T) {
+	govAddr := authtypes.NewModuleAddress(govtypes.ModuleName).String()
+
+	tests := []struct {
+		name           string
+		deposits       sdk.Coins
+		balance        sdk.Coins
+		wantViolations int
+	}{
+		{"matching", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), 0},
+		{"no deposits", nil, nil, 0},
+		{"different amount", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 9)), 1},
+		{"different denom", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("uother", 10)), 1},
+		{"extra denom", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10), sdk.NewInt64Coin("uother", 1)), 1},
+		{"missing balance", sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)), nil, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			govGenesis := govtypes.GenesisState{}
+			if !tc.deposits.Empty() {
+				govGenesis.Deposits = govtypes.Deposits{{ProposalId: 1, Depositor: govAddr, Amount: tc.deposits}}
+			}
+			balances := map[string]sdk.Coins{govAddr: tc.balance}
+
+			violations := govInvariants(govGenesis, balances)
+			require.Len(t, violations, tc.wantViolations)
+			for _, v := range violations {
+				require.Equal(t, "deposits", v.Invariant)
+			}
+		})
+	}
+}
+
+func TestDistributionInvariants(t *testing.T) {
+	distrAddr := authtypes.NewModuleAddress(distrtypes.ModuleName).String()
+
+	tests := []struct {
+		name           string
+		outstanding    sdk.DecCoins
+		communityPool  sdk.DecCoins
+		balance        sdk.Coins
+		wantInvariants []string
+	}{
+		{
+			name:          "matching with truncated decimals",
+			outstanding:   sdk.NewDecCoins(sdk.NewDecCoinFromDec("ubtsg", sdk.MustNewDecFromStr("5.5"))),
+			communityPool: sdk.NewDecCoins(sdk.NewDecCoinFromDec("ubtsg", sdk.MustNewDecFromStr("4.6"))),
+			balance:       sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 10)),
+		},
+		{
+			name:           "different denom",
+			outstanding:    sdk.NewDecCoins(sdk.NewInt64DecCoin("ubtsg", 10)),
+			balance:        sdk.NewCoins(sdk.NewInt64Coin("uother", 10)),
+			wantInvariants: []string{"module-account"},
+		},
+		{
+			name:           "different amount",
+			communityPool:  sdk.NewDecCoins(sdk.NewInt64DecCoin("ubtsg", 10)),
+			balance:        sdk.NewCoins(sdk.NewInt64Coin("ubtsg", 11)),
+			wantInvariants: []string{"module-account"},
+		},
+		{
+			name:           "negative outstanding",
+			outstanding:    sdk.DecCoins{{Denom: "ubtsg", Amount: sdk.NewDec(-1)}},
+			wantInvariants: []string{"nonnegative-outstanding"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			distrGenesis := distrtypes.GenesisState{
+				FeePool: distrtypes.FeePool{CommunityPool: tc.communityPool},
+			}
+			if tc.outstanding != nil {
+				distrGenesis.OutstandingRewards = []distrtypes.ValidatorOutstandingRewardsRecord{
+					{ValidatorAddress: "val", OutstandingRewards: tc.outstanding},
+				}
+			}
+			balances := map[string]sdk.Coins{distrAddr: tc.balance}
+
+			violations := distributionInvariants(distrGenesis, stakingtypes.GenesisState{}, balances)
+			var invariants []string
+			for _, v := range violations {
+				invariants = append(invariants, v.Invariant)
+			}
+			require.Equal(t, tc.wantInvariants, invariants)
+		})
+	}
+}
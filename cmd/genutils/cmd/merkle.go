@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	flagLeafFormat = "leaf-format"
+	flagHash       = "hash"
+	flagSortPairs  = "sort-pairs"
+
+	defaultLeafFormat = "{index}|{address}|{amount}"
+
+	// merkleScheme is the RFC 6962 domain separation: leaves are hashed with a
+	// 0x00 prefix and nodes with a 0x01 prefix, so that a node can never be
+	// passed off as a leaf.
+	merkleScheme = "rfc6962"
+)
+
+var (
+	merkleLeafPrefix = []byte{0x00}
+	merkleNodePrefix = []byte{0x01}
+)
+
+// MerkleProofs is the proof file written by merkle-airdrop generate. It holds
+// the tree parameters so that claims can be verified with the same encoding.
+type MerkleProofs struct {
+	Root       string        `json:"root"`
+	Scheme     string        `json:"scheme"`
+	Hash       string        `json:"hash"`
+	LeafFormat string        `json:"leaf_format"`
+	SortPairs  bool          `json:"sort_pairs"`
+	Claims     []MerkleClaim `json:"claims"`
+}
+
+// MerkleClaim is the proof of a single allocation.
+type MerkleClaim struct {
+	Index   uint64   `json:"index"`
+	Address string   `json:"address"`
+	Amount  sdk.Int  `json:"amount"`
+	Proof   []string `json:"proof"`
+}
+
+// merkleTree builds and verifies proofs for a tree of allocations.
+type merkleTree struct {
+	newHash    func() hash.Hash
+	leafFormat string
+	sortPairs  bool
+}
+
+// MerkleAirdropCmd returns merkle-airdrop cobra Command.
+func MerkleAirdropCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merkle-airdrop",
+		Short: "Merkle root and proof generation for airdrop allocations",
+	}
+
+	cmd.AddCommand(
+		generateMerkleProofsCmd(),
+		verifyMerkleProofCmd(),
+	)
+
+	return cmd
+}
+
+func generateMerkleProofsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate [allocations-file] [output-proofs-file]",
+		Short: "Compute the Merkle root of allocations and the proof of every address",
+		Long: `Compute the Merkle root of allocations and the proof of every address.
+
+The allocations are read either from the output of compute-airdrop or from a
+CSV file of address,amount lines. The index of an allocation is its position
+in the file.
+
+Each leaf is the hash of the --leaf-format template in which {index},
+{address} and {amount} are replaced by the allocation fields. Following
+RFC 6962, leaf data is prefixed with 0x00 and node data with 0x01 before being
+hashed. Levels with an odd number of nodes duplicate their last node. With
+--sort-pairs, the two children of a node are sorted before being hashed,
+otherwise their order follows the bits of the leaf index.
+
+Example:
+	genutils merkle-airdrop generate allocations.json proofs.json --hash keccak256
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tree, hashName, err := merkleTreeFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			allocations, err := loadAllocations(args[0])
+			if err != nil {
+				return err
+			}
+
+			proofs, err := tree.build(allocations)
+			if err != nil {
+				return err
+			}
+			proofs.Hash = hashName
+
+			fmt.Fprintf(cmd.OutOrStdout(), "root: %s\nscheme: %s (leaf = %s(0x00 || leaf), node = %s(0x01 || left || right))\n",
+				proofs.Root, proofs.Scheme, hashName, hashName)
+			return writeJSONFile(args[1], proofs)
+		},
+	}
+
+	cmd.Flags().String(flagLeafFormat, defaultLeafFormat, "Template of the hashed leaf data")
+	cmd.Flags().String(flagHash, "sha256", "Hash function (sha256|sha512_256|keccak256)")
+	cmd.Flags().Bool(flagSortPairs, true, "Sort sibling hashes before hashing them together")
+
+	return cmd
+}
+
+func verifyMerkleProofCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-proof [root] [proofs-file] [address]",
+		Short: "Verify the claim of an address against a Merkle root",
+		Long: `Verify the claim of an address from a proof file against a Merkle root. The
+hash function, leaf format and pair ordering are read from the proof file.
+Only proofs of the rfc6962 scheme are supported.
+`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bz, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to read proofs: %w", err)
+			}
+			var proofs MerkleProofs
+			if err := unmarshalYAMLOrJSON(args[1], bz, &proofs); err != nil {
+				return fmt.Errorf("failed to parse proofs %s: %w", args[1], err)
+			}
+
+			if proofs.Scheme != merkleScheme {
+				return fmt.Errorf("unsupported proof scheme %q, expected %s", proofs.Scheme, merkleScheme)
+			}
+			newHash, err := hashByName(proofs.Hash)
+			if err != nil {
+				return err
+			}
+			tree := merkleTree{newHash: newHash, leafFormat: proofs.LeafFormat, sortPairs: proofs.SortPairs}
+
+			for _, claim := range proofs.Claims {
+				if claim.Address != args[2] {
+					continue
+				}
+
+				ok, err := tree.verify(args[0], claim)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("claim of %s for %s is not part of root %s", claim.Address, claim.Amount, args[0])
+				}
+
+				cmd.Printf("claim of %s for %s is valid\n", claim.Address, claim.Amount)
+				return nil
+			}
+
+			return fmt.Errorf("no claim found for %s", args[2])
+		},
+	}
+
+	return cmd
+}
+
+func merkleTreeFromFlags(cmd *cobra.Command) (merkleTree, string, error) {
+	leafFormat, err := cmd.Flags().GetString(flagLeafFormat)
+	if err != nil {
+		return merkleTree{}, "", err
+	}
+	hashName, err := cmd.Flags().GetString(flagHash)
+	if err != nil {
+		return merkleTree{}, "", err
+	}
+	sortPairs, err := cmd.Flags().GetBool(flagSortPairs)
+	if err != nil {
+		return merkleTree{}, "", err
+	}
+
+	newHash, err := hashByName(hashName)
+	if err != nil {
+		return merkleTree{}, "", err
+	}
+
+	return merkleTree{newHash: newHash, leafFormat: leafFormat, sortPairs: sortPairs}, hashName, nil
+}
+
+func hashByName(name string) (func() hash.Hash, error) {
+	switch name {
+	case "sha256":
+		return sha256.New, nil
+	case "sha512_256":
+		return sha512.New512_256, nil
+	case "keccak256":
+		return sha3.NewLegacyKeccak256, nil
+	default:
+		return nil, fmt.Errorf("unknown hash function %q", name)
+	}
+}
+
+// loadAllocations reads allocations from a compute-airdrop output or from a
+// CSV file of address,amount lines.
+func loadAllocations(path string) ([]Allocation, error) {
+	var allocations []Allocation
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open allocations: %w", err)
+		}
+		defer f.Close()
+
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allocations %s: %w", path, err)
+		}
+		for i, record := range records {
+			if len(record) != 2 {
+				return nil, fmt.Errorf("allocations line %d: expected address,amount", i+1)
+			}
+			amount, ok := sdk.NewIntFromString(strings.TrimSpace(record[1]))
+			if !ok {
+				// skip an optional header line
+				if i == 0 {
+					continue
+				}
+				return nil, fmt.Errorf("allocations line %d: invalid amount %q", i+1, record[1])
+			}
+			allocations = append(allocations, Allocation{Address: strings.TrimSpace(record[0]), Amount: amount})
+		}
+	} else {
+		bz, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read allocations: %w", err)
+		}
+		var airdrop AirdropAllocations
+		if err := unmarshalYAMLOrJSON(path, bz, &airdrop); err != nil {
+			return nil, fmt.Errorf("failed to parse allocations %s: %w", path, err)
+		}
+		allocations = airdrop.Allocations
+	}
+
+	if len(allocations) == 0 {
+		return nil, errors.New("no allocations")
+	}
+	return allocations, nil
+}
+
+func (t merkleTree) hash(data ...[]byte) []byte {
+	h := t.newHash()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func (t merkleTree) leaf(index uint64, address string, amount sdk.Int) []byte {
+	data := strings.NewReplacer(
+		"{index}", strconv.FormatUint(index, 10),
+		"{address}", address,
+		"{amount}", amount.String(),
+	).Replace(t.leafFormat)
+	return t.hash(merkleLeafPrefix, []byte(data))
+}
+
+// node hashes two siblings, left being the one at the even position.
+func (t merkleTree) node(left, right []byte) []byte {
+	if t.sortPairs && bytes.Compare(left, right) > 0 {
+		left, right = right, left
+	}
+	return t.hash(merkleNodePrefix, left, right)
+}
+
+// build computes the root of the allocations and the proof of each of them.
+func (t merkleTree) build(allocations []Allocation) (MerkleProofs, error) {
+	seen := make(map[string]bool, len(allocations))
+	level := make([][]byte, len(allocations))
+	claims := make([]MerkleClaim, len(allocations))
+	for i, alloc := range allocations {
+		if seen[alloc.Address] {
+			return MerkleProofs{}, fmt.Errorf("duplicate allocation for %s", alloc.Address)
+		}
+		seen[alloc.Address] = true
+		if alloc.Amount.IsNil() || !alloc.Amount.IsPositive() {
+			return MerkleProofs{}, fmt.Errorf("allocation of %s must be positive", alloc.Address)
+		}
+
+		level[i] = t.leaf(uint64(i), alloc.Address, alloc.Amount)
+		claims[i] = MerkleClaim{Index: uint64(i), Address: alloc.Address, Amount: alloc.Amount, Proof: []string{}}
+	}
+
+	// positions[i] is the position of the ancestor of leaf i in the level
+	positions := make([]int, len(allocations))
+	for i := range positions {
+		positions[i] = i
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		for i, pos := range positions {
+			claims[i].Proof = append(claims[i].Proof, hex.EncodeToString(level[pos^1]))
+			positions[i] = pos / 2
+		}
+
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = t.node(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+
+	return MerkleProofs{
+		Root:       hex.EncodeToString(level[0]),
+		Scheme:     merkleScheme,
+		LeafFormat: t.leafFormat,
+		SortPairs:  t.sortPairs,
+		Claims:     claims,
+	}, nil
+}
+
+// verify checks that claim is part of the tree with the given hex root.
+func (t merkleTree) verify(root string, claim MerkleClaim) (bool, error) {
+	expected, err := hex.DecodeString(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+
+	computed := t.leaf(claim.Index, claim.Address, claim.Amount)
+	pos := claim.Index
+	for _, sibling := range claim.Proof {
+		siblingBz, err := hex.DecodeString(sibling)
+		if err != nil {
+			return false, fmt.Errorf("invalid proof: %w", err)
+		}
+		if pos%2 == 0 {
+			computed = t.node(computed, siblingBz)
+		} else {
+			computed = t.node(siblingBz, computed)
+		}
+		pos /= 2
+	}
+
+	return bytes.Equal(computed, expected), nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestMerkleTreeBuildVerify(t *testing.T) {
+	allocations := func(n int) []Allocation {
+		allocs := make([]Allocation, n)
+		for i := range allocs {
+			allocs[i] = Allocation{Address: fmt.Sprintf("addr%02d", i), Amount: sdk.NewInt(int64(1000 + i))}
+		}
+		return allocs
+	}
+	sha256Tree := merkleTree{newHash: sha256.New, leafFormat: defaultLeafFormat}
+	sortedTree := merkleTree{newHash: sha3.NewLegacyKeccak256, leafFormat: defaultLeafFormat, sortPairs: true}
+
+	tests := []struct {
+		name string
+		tree merkleTree
+		n    int
+		// depth is the expected proof length
+		depth int
+	}{
+		{"single leaf", sha256Tree, 1, 0},
+		{"two leaves", sha256Tree, 2, 1},
+		{"odd leaves", sha256Tree, 3, 2},
+		{"five leaves", sha256Tree, 5, 3},
+		{"power of two", sha256Tree, 8, 3},
+		{"sorted pairs", sortedTree, 7, 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocs := allocations(tc.n)
+			proofs, err := tc.tree.build(allocs)
+			require.NoError(t, err)
+			require.Len(t, proofs.Claims, tc.n)
+
+			for i, claim := range proofs.Claims {
+				require.Equal(t, uint64(i), claim.Index)
+				require.Equal(t, allocs[i].Address, claim.Address)
+				require.Len(t, claim.Proof, tc.depth)
+
+				ok, err := tc.tree.verify(proofs.Root, claim)
+				require.NoError(t, err)
+				require.True(t, ok, "claim %d", i)
+
+				tampered := claim
+				tampered.Amount = claim.Amount.AddRaw(1)
+				ok, err = tc.tree.verify(proofs.Root, tampered)
+				require.NoError(t, err)
+				require.False(t, ok, "tampered claim %d", i)
+			}
+
+			// the root depends on the leaf format
+			other := tc.tree
+			other.leafFormat = "{address}:{amount}"
+			otherProofs, err := other.build(allocs)
+			require.NoError(t, err)
+			require.NotEqual(t, proofs.Root, otherProofs.Root)
+		})
+	}
+}
+
+func TestMerkleTreeDomainSeparation(t *testing.T) {
+	tree := merkleTree{newHash: sha256.New, leafFormat: defaultLeafFormat}
+	sum := func(data ...[]byte) []byte {
+		h := sha256.New()
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+
+	leaf := tree.leaf(3, "addr03", sdk.NewInt(1003))
+	require.Equal(t, sum([]byte{0x00}, []byte("3|addr03|1003")), leaf)
+
+	other := tree.leaf(4, "addr04", sdk.NewInt(1004))
+	node := tree.node(leaf, other)
+	require.Equal(t, sum([]byte{0x01}, leaf, other), node)
+
+	// a leaf whose data is the concatenation of two children is not a node
+	forged := merkleTree{newHash: sha256.New, leafFormat: string(leaf) + string(other)}
+	require.NotEqual(t, node, forged.leaf(0, "", sdk.ZeroInt()))
+
+	proofs, err := tree.build([]Allocation{{Address: "addr00", Amount: sdk.NewInt(1)}})
+	require.NoError(t, err)
+	require.Equal(t, merkleScheme, proofs.Scheme)
+}
+
+func TestMerkleTreeBuildErrors(t *testing.T) {
+	tree := merkleTree{newHash: sha256.New, leafFormat: defaultLeafFormat}
+
+	tests := []struct {
+		name        string
+		allocations []Allocation
+	}{
+		{"duplicate address", []Allocation{
+			{Address: "addr00", Amount: sdk.NewInt(1)},
+			{Address: "addr00", Amount: sdk.NewInt(2)},
+		}},
+		{"zero amount", []Allocation{{Address: "addr00", Amount: sdk.ZeroInt()}}},
+		{"nil amount", []Allocation{{Address: "addr00"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tree.build(tc.allocations)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := tree.verify("not hex", MerkleClaim{Amount: sdk.NewInt(1)})
+	require.Error(t, err)
+}
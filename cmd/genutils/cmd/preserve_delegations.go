@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// repointDelegations replaces the validators of stakingGenesis with
+// newValidators while keeping every delegation, unbonding delegation and
+// their amounts. Each exported validator is mapped to a new one through
+// validatorMap, falling back to a round-robin assignment in export order.
+//
+// Delegation shares are converted to tokens with the exchange rate of the old
+// validator and become shares of the new validator at a 1:1 rate. The tokens
+// of the new validators are the sum of the tokens of the validators mapped to
+// them. Redelegations are dropped as their source and destination may collapse
+// into the same validator.
+func repointDelegations(stakingGenesis *stakingtypes.GenesisState, newValidators []stakingtypes.Validator, validatorMap map[string]string) error {
+	newIndex := make(map[string]int, len(newValidators))
+	for i, val := range newValidators {
+		newIndex[val.OperatorAddress] = i
+	}
+
+	oldValidators := make(map[string]stakingtypes.Validator, len(stakingGenesis.Validators))
+	mapping := make(map[string]int, len(stakingGenesis.Validators))
+	next := 0
+	for _, val := range stakingGenesis.Validators {
+		oldValidators[val.OperatorAddress] = val
+
+		if newOperator, ok := validatorMap[val.OperatorAddress]; ok {
+			mapping[val.OperatorAddress] = newIndex[newOperator]
+		} else {
+			mapping[val.OperatorAddress] = next % len(newValidators)
+			next++
+		}
+
+		target := &newValidators[mapping[val.OperatorAddress]]
+		target.Tokens = target.Tokens.Add(val.Tokens)
+	}
+
+	for oldOperator := range validatorMap {
+		if _, ok := oldValidators[oldOperator]; !ok {
+			return fmt.Errorf("validator_map: %s is not an exported validator", oldOperator)
+		}
+	}
+
+	// merge delegations of a delegator whose validators map to the same new
+	// validator, keeping the export order
+	type delegationKey struct{ delegator, validator string }
+	merged := make(map[delegationKey]int)
+	var delegations []stakingtypes.Delegation
+	for _, del := range stakingGenesis.Delegations {
+		oldVal, ok := oldValidators[del.ValidatorAddress]
+		if !ok {
+			return fmt.Errorf("delegation of %s references unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+		}
+
+		target := &newValidators[mapping[del.ValidatorAddress]]
+		shares := oldVal.TokensFromShares(del.Shares)
+		target.DelegatorShares = target.DelegatorShares.Add(shares)
+
+		key := delegationKey{del.DelegatorAddress, target.OperatorAddress}
+		if i, ok := merged[key]; ok {
+			delegations[i].Shares = delegations[i].Shares.Add(shares)
+			continue
+		}
+		merged[key] = len(delegations)
+		delegations = append(delegations, stakingtypes.Delegation{
+			DelegatorAddress: del.DelegatorAddress,
+			ValidatorAddress: target.OperatorAddress,
+			Shares:           shares,
+		})
+	}
+
+	ubdIndex := make(map[delegationKey]int)
+	var unbondingDelegations []stakingtypes.UnbondingDelegation
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		idx, ok := mapping[ubd.ValidatorAddress]
+		if !ok {
+			return fmt.Errorf("unbonding delegation of %s references unknown validator %s", ubd.DelegatorAddress, ubd.ValidatorAddress)
+		}
+
+		key := delegationKey{ubd.DelegatorAddress, newValidators[idx].OperatorAddress}
+		if i, ok := ubdIndex[key]; ok {
+			entries := append(unbondingDelegations[i].Entries, ubd.Entries...)
+			sort.SliceStable(entries, func(a, b int) bool {
+				return entries[a].CompletionTime.Before(entries[b].CompletionTime)
+			})
+			unbondingDelegations[i].Entries = entries
+			continue
+		}
+		ubdIndex[key] = len(unbondingDelegations)
+		ubd.ValidatorAddress = key.validator
+		unbondingDelegations = append(unbondingDelegations, ubd)
+	}
+
+	for _, val := range newValidators {
+		if val.Tokens.IsZero() {
+			return fmt.Errorf("no exported validator is mapped to %s", val.OperatorAddress)
+		}
+	}
+
+	stakingGenesis.Validators = newValidators
+	stakingGenesis.Delegations = delegations
+	if stakingGenesis.Delegations == nil {
+		stakingGenesis.Delegations = []stakingtypes.Delegation{}
+	}
+	stakingGenesis.UnbondingDelegations = unbondingDelegations
+	if stakingGenesis.UnbondingDelegations == nil {
+		stakingGenesis.UnbondingDelegations = []stakingtypes.UnbondingDelegation{}
+	}
+	stakingGenesis.Redelegations = []stakingtypes.Redelegation{}
+
+	return nil
+}
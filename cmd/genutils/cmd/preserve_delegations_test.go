@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepointDelegations(t *testing.T) {
+	oldValidator := func(operator string, tokens, shares int64) stakingtypes.Validator {
+		return stakingtypes.Validator{
+			OperatorAddress: operator,
+			Tokens:          sdk.NewInt(tokens),
+			DelegatorShares: sdk.NewDec(shares),
+		}
+	}
+	newValidators := func(operators ...string) []stakingtypes.Validator {
+		vals := make([]stakingtypes.Validator, len(operators))
+		for i, operator := range operators {
+			vals[i] = stakingtypes.Validator{
+				OperatorAddress: operator,
+				Tokens:          sdk.ZeroInt(),
+				DelegatorShares: sdk.ZeroDec(),
+			}
+		}
+		return vals
+	}
+	delegation := func(delegator, validator string, shares int64) stakingtypes.Delegation {
+		return stakingtypes.Delegation{DelegatorAddress: delegator, ValidatorAddress: validator, Shares: sdk.NewDec(shares)}
+	}
+	unbonding := func(delegator, validator string, completion time.Time, balance int64) stakingtypes.UnbondingDelegation {
+		return stakingtypes.UnbondingDelegation{
+			DelegatorAddress: delegator,
+			ValidatorAddress: validator,
+			Entries: []stakingtypes.UnbondingDelegationEntry{
+				stakingtypes.NewUnbondingDelegationEntry(1, completion, sdk.NewInt(balance)),
+			},
+		}
+	}
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	exported := func() stakingtypes.GenesisState {
+		return stakingtypes.GenesisState{
+			Validators: []stakingtypes.Validator{
+				oldValidator("valA", 100, 100),
+				// half of a token per share
+				oldValidator("valB", 50, 100),
+				oldValidator("valC", 30, 30),
+			},
+			Delegations: []stakingtypes.Delegation{
+				delegation("del1", "valA", 100),
+				delegation("del1", "valB", 100),
+				delegation("del2", "valC", 30),
+			},
+			UnbondingDelegations: []stakingtypes.UnbondingDelegation{
+				unbonding("del2", "valA", t0.Add(2*time.Hour), 5),
+				unbonding("del2", "valB", t0.Add(time.Hour), 7),
+			},
+			Redelegations: []stakingtypes.Redelegation{
+				{DelegatorAddress: "del1", ValidatorSrcAddress: "valA", ValidatorDstAddress: "valB"},
+			},
+		}
+	}
+
+	type want struct {
+		tokens      map[string]int64
+		delegations []stakingtypes.Delegation
+		unbondings  map[string][]int64
+	}
+	tests := []struct {
+		name          string
+		newOperators  []string
+		validatorMap  map[string]string
+		want          want
+		wantErrSubstr string
+	}{
+		{
+			name:         "round robin",
+			newOperators: []string{"valX", "valY"},
+			want: want{
+				// valA and valC go to valX, valB goes to valY
+				tokens: map[string]int64{"valX": 130, "valY": 50},
+				delegations: []stakingtypes.Delegation{
+					delegation("del1", "valX", 100),
+					delegation("del1", "valY", 50),
+					delegation("del2", "valX", 30),
+				},
+				unbondings: map[string][]int64{"del2/valX": {5}, "del2/valY": {7}},
+			},
+		},
+		{
+			name:         "mapped validators merge delegations",
+			newOperators: []string{"valX", "valY"},
+			validatorMap: map[string]string{"valB": "valX"},
+			want: want{
+				// valB is mapped to valX, then valA and valC are assigned
+				// round-robin
+				tokens: map[string]int64{"valX": 150, "valY": 30},
+				delegations: []stakingtypes.Delegation{
+					delegation("del1", "valX", 150),
+					delegation("del2", "valY", 30),
+				},
+				unbondings: map[string][]int64{"del2/valX": {7, 5}},
+			},
+		},
+		{
+			name:          "unknown validator in map",
+			newOperators:  []string{"valX"},
+			validatorMap:  map[string]string{"valZ": "valX"},
+			wantErrSubstr: "valZ is not an exported validator",
+		},
+		{
+			name:          "new validator without exported validator",
+			newOperators:  []string{"valX", "valY"},
+			validatorMap:  map[string]string{"valA": "valX", "valB": "valX", "valC": "valX"},
+			wantErrSubstr: "no exported validator is mapped to valY",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stakingGenesis := exported()
+			err := repointDelegations(&stakingGenesis, newValidators(tc.newOperators...), tc.validatorMap)
+			if tc.wantErrSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.wantErrSubstr)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, stakingGenesis.Validators, len(tc.newOperators))
+			for _, val := range stakingGenesis.Validators {
+				require.Equal(t, tc.want.tokens[val.OperatorAddress], val.Tokens.Int64(), val.OperatorAddress)
+				// new validators start at one share per token
+				require.True(t, val.DelegatorShares.Equal(val.Tokens.ToDec()), val.OperatorAddress)
+			}
+
+			require.Len(t, stakingGenesis.Delegations, len(tc.want.delegations))
+			for i, del := range stakingGenesis.Delegations {
+				want := tc.want.delegations[i]
+				require.Equal(t, want.DelegatorAddress, del.DelegatorAddress)
+				require.Equal(t, want.ValidatorAddress, del.ValidatorAddress)
+				require.True(t, want.Shares.Equal(del.Shares), "delegation %d: got %s shares, want %s", i, del.Shares, want.Shares)
+			}
+
+			require.Len(t, stakingGenesis.UnbondingDelegations, len(tc.want.unbondings))
+			for _, ubd := range stakingGenesis.UnbondingDelegations {
+				key := ubd.DelegatorAddress + "/" + ubd.ValidatorAddress
+				balances := make([]int64, len(ubd.Entries))
+				for i, entry := range ubd.Entries {
+					balances[i] = entry.Balance.Int64()
+				}
+				// entries are sorted by completion time
+				require.Equal(t, tc.want.unbondings[key], balances, key)
+			}
+
+			require.Empty(t, stakingGenesis.Redelegations)
+		})
+	}
+}
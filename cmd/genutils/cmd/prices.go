@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// loadPriceTable reads a price table from a CSV file with denom, price and
+// decimals columns, or from a YAML/JSON list of AssetInfo.
+func loadPriceTable(path string) (map[string]AssetInfo, error) {
+	var assets []AssetInfo
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open price table: %w", err)
+		}
+		defer f.Close()
+
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+		}
+
+		for i, record := range records {
+			if len(record) != 3 {
+				return nil, fmt.Errorf("price table line %d: expected denom,price,decimals", i+1)
+			}
+			// skip an optional header line
+			if i == 0 && strings.EqualFold(strings.TrimSpace(record[1]), "price") {
+				continue
+			}
+
+			price, err := sdk.NewDecFromStr(strings.TrimSpace(record[1]))
+			if err != nil {
+				return nil, fmt.Errorf("price table line %d: invalid price: %w", i+1, err)
+			}
+			decimals, err := strconv.ParseInt(strings.TrimSpace(record[2]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("price table line %d: invalid decimals: %w", i+1, err)
+			}
+			assets = append(assets, AssetInfo{Denom: strings.TrimSpace(record[0]), Price: price, Decimals: decimals})
+		}
+	} else {
+		bz, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read price table: %w", err)
+		}
+		if err := unmarshalYAMLOrJSON(path, bz, &assets); err != nil {
+			return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+		}
+	}
+
+	prices := make(map[string]AssetInfo, len(assets))
+	for _, asset := range assets {
+		if err := sdk.ValidateDenom(asset.Denom); err != nil {
+			return nil, fmt.Errorf("invalid price table denom: %w", err)
+		}
+		if asset.Price.IsNil() || asset.Price.IsNegative() {
+			return nil, fmt.Errorf("invalid price for %s", asset.Denom)
+		}
+		if asset.Decimals < 0 || asset.Decimals > sdk.Precision {
+			return nil, fmt.Errorf("invalid decimals for %s: %d", asset.Denom, asset.Decimals)
+		}
+		if _, ok := prices[asset.Denom]; ok {
+			return nil, fmt.Errorf("duplicate price for %s", asset.Denom)
+		}
+		prices[asset.Denom] = asset
+	}
+
+	return prices, nil
+}
+
+// usdValue returns the USD value of amount base units of the asset.
+func (a AssetInfo) usdValue(amount sdk.Int) sdk.Dec {
+	return amount.ToDec().Mul(a.Price).QuoInt(sdk.NewIntWithDecimal(1, int(a.Decimals)))
+}
+
+// valueSnapshot sets the UsdValue of every snapshot account to the value of
+// its staked amount, denominated in bondDenom, plus the value of its liquid
+// balances. Accounts without stake but with a positive value are added to the
+// snapshot, except for the excluded ones such as module accounts. Denoms
+// missing from prices are ignored. Values are truncated to whole dollars.
+func valueSnapshot(snapshot *DeriveSnapshotStaked, balances []banktypes.Balance, bondDenom string, prices map[string]AssetInfo, excluded map[string]bool) {
+	values := make(map[string]sdk.Dec)
+	staked := make(map[string]sdk.Int, len(snapshot.Accounts))
+	for _, acc := range snapshot.Accounts {
+		staked[acc.Address] = acc.Staked
+		values[acc.Address] = sdk.ZeroDec()
+		if asset, ok := prices[bondDenom]; ok {
+			values[acc.Address] = asset.usdValue(acc.Staked)
+		}
+	}
+
+	for _, balance := range balances {
+		if excluded[balance.Address] {
+			continue
+		}
+		value, ok := values[balance.Address]
+		if !ok {
+			value = sdk.ZeroDec()
+		}
+		for _, coin := range balance.Coins {
+			if asset, ok := prices[coin.Denom]; ok {
+				value = value.Add(asset.usdValue(coin.Amount))
+			}
+		}
+		values[balance.Address] = value
+	}
+
+	accounts := make([]StakedAccount, 0, len(values))
+	for addr, value := range values {
+		usdValue := value.TruncateInt()
+		stake, ok := staked[addr]
+		if !ok {
+			if !usdValue.IsPositive() {
+				continue
+			}
+			stake = sdk.ZeroInt()
+		}
+		accounts = append(accounts, StakedAccount{Address: addr, Staked: stake, UsdValue: usdValue})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Address < accounts[j].Address
+	})
+
+	snapshot.Accounts = accounts
+	snapshot.NumberAccounts = uint64(len(accounts))
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/app"
+)
+
+const (
+	flagChainProfile = "chain-profile"
+
+	// envChainProfiles overrides the path of the chain profiles file.
+	envChainProfiles = "GENUTILS_CHAIN_PROFILES"
+)
+
+// chainProfilesFile lists the chain profiles defined in addition to the
+// built-in ones. Default names the profile used when --chain-profile is not
+// given.
+type chainProfilesFile struct {
+	Default  string             `json:"default"`
+	Profiles []app.ChainProfile `json:"profiles"`
+}
+
+// defaultChainProfilesPath returns the path of the chain profiles file, which
+// need not exist.
+func defaultChainProfilesPath() string {
+	if path := os.Getenv(envChainProfiles); path != "" {
+		return path
+	}
+
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userHomeDir, ".genutils", "chains.yaml")
+}
+
+// loadChainProfiles reads the chain profiles file at path, if any.
+func loadChainProfiles(path string) (chainProfilesFile, error) {
+	var profiles chainProfilesFile
+	if path == "" {
+		return profiles, nil
+	}
+
+	bz, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return profiles, fmt.Errorf("failed to read chain profiles: %w", err)
+	}
+
+	if err := unmarshalYAMLOrJSON(path, bz, &profiles); err != nil {
+		return profiles, fmt.Errorf("failed to parse chain profiles %s: %w", path, err)
+	}
+	for _, p := range profiles.Profiles {
+		if err := p.Validate(); err != nil {
+			return profiles, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return profiles, nil
+}
+
+// resolveChainProfile returns the profile selected by nameOrPath, which is
+// either the name of a profile of the profiles file or a built-in profile, or
+// the path of a YAML or JSON file holding a single profile. An empty
+// nameOrPath selects the default of the profiles file, or BitSong.
+func resolveChainProfile(nameOrPath, profilesPath string) (app.ChainProfile, error) {
+	profiles, err := loadChainProfiles(profilesPath)
+	if err != nil {
+		return app.ChainProfile{}, err
+	}
+
+	if nameOrPath == "" {
+		nameOrPath = profiles.Default
+	}
+	if nameOrPath == "" {
+		return app.BitSongProfile, nil
+	}
+
+	for _, p := range append(profiles.Profiles, app.BuiltinProfiles...) {
+		if p.Name == nameOrPath {
+			return p, nil
+		}
+	}
+
+	bz, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		return app.ChainProfile{}, fmt.Errorf("unknown chain profile %s", nameOrPath)
+	}
+	var profile app.ChainProfile
+	if err := unmarshalYAMLOrJSON(nameOrPath, bz, &profile); err != nil {
+		return profile, fmt.Errorf("failed to parse chain profile %s: %w", nameOrPath, err)
+	}
+	return profile, profile.Validate()
+}
+
+// applyChainProfile makes the profile selected by --chain-profile the active
+// one and sets the SDK config from it. The --home flag defaults to the home
+// directory of the profile unless given.
+func applyChainProfile(cmd *cobra.Command) error {
+	nameOrPath, err := cmd.Flags().GetString(flagChainProfile)
+	if err != nil {
+		return err
+	}
+	profile, err := resolveChainProfile(nameOrPath, defaultChainProfilesPath())
+	if err != nil {
+		return err
+	}
+
+	app.SetChainProfile(profile)
+	app.SetConfig()
+
+	if home := cmd.Flags().Lookup(flags.FlagHome); home != nil && !home.Changed {
+		if err := home.Value.Set(app.DefaultNodeHome); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"gopkg.in/yaml.v2"
+
+	"github.com/go-btsg/genutils/app"
+)
+
+const (
+	defaultForkSelfBalance     = 1000_000_000
+	defaultForkUnbondedMoniker = "unbonded"
+
+	defaultCommissionRate          = "0.01"
+	defaultCommissionMaxRate       = "0.10"
+	defaultCommissionMaxChangeRate = "0.01"
+)
+
+// ForkRecipe describes every input of export-upgraded-genesis so that a fork
+// can be reproduced from a file kept under version control.
+type ForkRecipe struct {
+	ChainID         string                     `json:"chain_id"`
+	GenesisTime     string                     `json:"genesis_time"`
+	InitialHeight   int64                      `json:"initial_height"`
+	Denom           string                     `json:"denom"`
+	UnbondedMoniker string                     `json:"unbonded_moniker"`
+	Validators      []RecipeValidator          `json:"validators"`
+	Accounts        []RecipeAccount            `json:"accounts"`
+	ParamOverrides  map[string]json.RawMessage `json:"param_overrides"`
+
+	// PreserveDelegations keeps the exported delegations and re-points them
+	// to the recipe validators instead of replacing them with self
+	// delegations. ValidatorMap maps exported operator addresses to recipe
+	// operator addresses; unmapped validators are assigned round-robin.
+	PreserveDelegations bool              `json:"preserve_delegations"`
+	ValidatorMap        map[string]string `json:"validator_map"`
+}
+
+// RecipeValidator is a replacement validator injected into the forked state.
+type RecipeValidator struct {
+	Owner       string           `json:"owner"`
+	Operator    string           `json:"operator"`
+	PubKey      json.RawMessage  `json:"pubkey"`
+	Moniker     string           `json:"moniker"`
+	Weight      uint64           `json:"weight"`
+	SelfBalance string           `json:"self_balance"`
+	Commission  RecipeCommission `json:"commission"`
+}
+
+// RecipeCommission holds the commission rates of a RecipeValidator as decimal
+// strings.
+type RecipeCommission struct {
+	Rate          string `json:"rate"`
+	MaxRate       string `json:"max_rate"`
+	MaxChangeRate string `json:"max_change_rate"`
+}
+
+// RecipeAccount is an account funded with the given coins in the forked state.
+type RecipeAccount struct {
+	Address string `json:"address"`
+	Coins   string `json:"coins"`
+}
+
+// loadForkRecipe reads a recipe from a YAML or JSON file and fills in the
+// defaults for every omitted field.
+func loadForkRecipe(path string) (ForkRecipe, error) {
+	var recipe ForkRecipe
+
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return recipe, fmt.Errorf("failed to read recipe: %w", err)
+	}
+
+	if err := unmarshalYAMLOrJSON(path, bz, &recipe); err != nil {
+		return recipe, fmt.Errorf("failed to parse recipe %s: %w", path, err)
+	}
+
+	recipe.setDefaults()
+	return recipe, recipe.Validate()
+}
+
+// newLegacyForkRecipe builds the recipe equivalent to the positional arguments
+// accepted by export-upgraded-genesis before recipes were introduced.
+func newLegacyForkRecipe(owner, operator, pubKey string) ForkRecipe {
+	recipe := ForkRecipe{
+		Validators: []RecipeValidator{{
+			Owner:    owner,
+			Operator: operator,
+			PubKey:   json.RawMessage(pubKey),
+			Moniker:  "moniker1",
+		}},
+	}
+	recipe.setDefaults()
+	return recipe
+}
+
+func (r *ForkRecipe) setDefaults() {
+	if r.Denom == "" {
+		r.Denom = app.ActiveProfile.BondDenom
+	}
+	if r.UnbondedMoniker == "" {
+		r.UnbondedMoniker = defaultForkUnbondedMoniker
+	}
+
+	for i := range r.Validators {
+		val := &r.Validators[i]
+		if val.Weight == 0 {
+			val.Weight = 1
+		}
+		if val.SelfBalance == "" {
+			val.SelfBalance = sdk.NewInt64Coin(r.Denom, defaultForkSelfBalance).String()
+		}
+		if val.Commission.Rate == "" {
+			val.Commission.Rate = defaultCommissionRate
+		}
+		if val.Commission.MaxRate == "" {
+			val.Commission.MaxRate = defaultCommissionMaxRate
+		}
+		if val.Commission.MaxChangeRate == "" {
+			val.Commission.MaxChangeRate = defaultCommissionMaxChangeRate
+		}
+	}
+}
+
+// Validate performs a stateless check of the recipe.
+func (r ForkRecipe) Validate() error {
+	if len(r.ChainID) > tmtypes.MaxChainIDLen {
+		return fmt.Errorf("chain_id is longer than %d characters", tmtypes.MaxChainIDLen)
+	}
+	if r.GenesisTime != "" {
+		if _, err := parseGenesisTime(r.GenesisTime, time.Now()); err != nil {
+			return err
+		}
+	}
+	if r.InitialHeight < 0 {
+		return fmt.Errorf("initial_height %d must not be negative", r.InitialHeight)
+	}
+
+	if err := sdk.ValidateDenom(r.Denom); err != nil {
+		return fmt.Errorf("invalid denom: %w", err)
+	}
+
+	if len(r.Validators) == 0 {
+		return errors.New("recipe must define at least one validator")
+	}
+
+	operators := make(map[string]bool, len(r.Validators))
+	pubKeys := make(map[string]bool, len(r.Validators))
+	for i, val := range r.Validators {
+		if operators[val.Operator] {
+			return fmt.Errorf("validator %d: duplicate operator %s", i, val.Operator)
+		}
+		operators[val.Operator] = true
+
+		if pubKeys[string(val.pubKeyJSON())] {
+			return fmt.Errorf("validator %d: duplicate pubkey", i)
+		}
+		pubKeys[string(val.pubKeyJSON())] = true
+
+		if _, err := sdk.AccAddressFromBech32(val.Owner); err != nil {
+			return fmt.Errorf("validator %d: invalid owner: %w", i, err)
+		}
+		if _, err := sdk.ValAddressFromBech32(val.Operator); err != nil {
+			return fmt.Errorf("validator %d: invalid operator: %w", i, err)
+		}
+		if len(val.PubKey) == 0 {
+			return fmt.Errorf("validator %d: missing pubkey", i)
+		}
+		if val.Moniker == "" {
+			return fmt.Errorf("validator %d: missing moniker", i)
+		}
+		if _, err := sdk.ParseCoinsNormalized(val.SelfBalance); err != nil {
+			return fmt.Errorf("validator %d: invalid self_balance: %w", i, err)
+		}
+		if _, err := val.Commission.toCommission(); err != nil {
+			return fmt.Errorf("validator %d: %w", i, err)
+		}
+	}
+
+	for oldOperator, newOperator := range r.ValidatorMap {
+		if _, err := sdk.ValAddressFromBech32(oldOperator); err != nil {
+			return fmt.Errorf("validator_map: invalid operator %s: %w", oldOperator, err)
+		}
+		if !operators[newOperator] {
+			return fmt.Errorf("validator_map: %s is mapped to %s which is not a recipe validator", oldOperator, newOperator)
+		}
+	}
+
+	for i, acc := range r.Accounts {
+		if _, err := sdk.AccAddressFromBech32(acc.Address); err != nil {
+			return fmt.Errorf("account %d: invalid address: %w", i, err)
+		}
+		if _, err := sdk.ParseCoinsNormalized(acc.Coins); err != nil {
+			return fmt.Errorf("account %d: invalid coins: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// parseGenesisTime parses an RFC 3339 time, or a time relative to now such as
+// "now", "now+24h" or "now - 30m". Relative times are truncated to the second.
+func parseGenesisTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "now") {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid genesis time %q: expected an RFC 3339 time or now[+-duration]", s)
+		}
+		return t.UTC(), nil
+	}
+
+	now = now.UTC().Truncate(time.Second)
+	offset := strings.ReplaceAll(strings.TrimPrefix(s, "now"), " ", "")
+	if offset == "" {
+		return now, nil
+	}
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.Time{}, fmt.Errorf("invalid genesis time %q: expected now+duration or now-duration", s)
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid genesis time %q: %w", s, err)
+	}
+	return now.Add(d), nil
+}
+
+// validatorWeights returns the stake weight of every validator, in order.
+func (r ForkRecipe) validatorWeights() []uint64 {
+	weights := make([]uint64, len(r.Validators))
+	for i, val := range r.Validators {
+		weights[i] = val.Weight
+	}
+	return weights
+}
+
+// pubKeyJSON returns the consensus pubkey as JSON. The pubkey may be given
+// either as an object or as a string holding the JSON object, the latter being
+// the format of the legacy positional argument.
+func (v RecipeValidator) pubKeyJSON() []byte {
+	var s string
+	if err := json.Unmarshal(v.PubKey, &s); err == nil {
+		return []byte(s)
+	}
+	return v.PubKey
+}
+
+// toCommission parses the commission rates and checks them for consistency.
+func (c RecipeCommission) toCommission() (stakingtypes.Commission, error) {
+	var rates [3]sdk.Dec
+	for i, s := range []string{c.Rate, c.MaxRate, c.MaxChangeRate} {
+		dec, err := sdk.NewDecFromStr(s)
+		if err != nil {
+			return stakingtypes.Commission{}, fmt.Errorf("invalid commission rate %q: %w", s, err)
+		}
+		rates[i] = dec
+	}
+
+	commission := stakingtypes.NewCommission(rates[0], rates[1], rates[2])
+	if err := commission.Validate(); err != nil {
+		return stakingtypes.Commission{}, fmt.Errorf("invalid commission: %w", err)
+	}
+	return commission, nil
+}
+
+// unmarshalYAMLOrJSON decodes bz into v, treating files with a .yaml or .yml
+// extension as YAML. The YAML document is converted to JSON first so that the
+// json struct tags apply to both formats.
+func unmarshalYAMLOrJSON(path string, bz []byte, v interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc interface{}
+		if err := yaml.Unmarshal(bz, &doc); err != nil {
+			return err
+		}
+
+		doc, err := yamlToJSONValue(doc)
+		if err != nil {
+			return err
+		}
+
+		bz, err = json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(bz, v)
+}
+
+// yamlToJSONValue converts the map[interface{}]interface{} values produced by
+// the YAML decoder into map[string]interface{} values encodable as JSON.
+func yamlToJSONValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported non-string key %v", k)
+			}
+			conv, err := yamlToJSONValue(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = conv
+		}
+		return m, nil
+
+	case []interface{}:
+		for i, val := range v {
+			conv, err := yamlToJSONValue(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = conv
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// mergeJSON deep merges patch into base. Objects are merged key by key while
+// any other valid JSON value in patch replaces the one in base.
+func mergeJSON(base, patch json.RawMessage) (json.RawMessage, error) {
+	patchObj, err := unmarshalJSONObject(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	if patchObj == nil {
+		return patch, nil
+	}
+	baseObj, err := unmarshalJSONObject(base)
+	if err != nil {
+		return nil, err
+	}
+	if baseObj == nil {
+		return patch, nil
+	}
+
+	for k, v := range patchObj {
+		if existing, ok := baseObj[k]; ok {
+			merged, err := mergeJSON(existing, v)
+			if err != nil {
+				return nil, err
+			}
+			baseObj[k] = merged
+			continue
+		}
+		baseObj[k] = v
+	}
+
+	return json.Marshal(baseObj)
+}
+
+// unmarshalJSONObject decodes bz as a JSON object. It returns a nil map
+// without error when bz is valid JSON but not an object.
+func unmarshalJSONObject(bz json.RawMessage) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(bz, &obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return obj, nil
+}
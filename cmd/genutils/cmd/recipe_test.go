@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGenesisTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 15, 500, time.FixedZone("CEST", 2*3600))
+	nowUTC := time.Date(2026, 10, 16, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"rfc3339", "2030-01-02T03:04:05Z", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"rfc3339 nano", "2030-01-02T03:04:05.123456789Z", time.Date(2030, 1, 2, 3, 4, 5, 123456789, time.UTC), false},
+		{"rfc3339 offset to utc", "2030-01-02T05:04:05+02:00", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"now", "now", nowUTC, false},
+		{"now plus", "now+24h", nowUTC.Add(24 * time.Hour), false},
+		{"now minus with spaces", " now - 30m ", nowUTC.Add(-30 * time.Minute), false},
+		{"now compound duration", "now+1h30m", nowUTC.Add(90 * time.Minute), false},
+		{"missing sign", "now24h", time.Time{}, true},
+		{"invalid duration", "now+1d", time.Time{}, true},
+		{"invalid time", "tomorrow", time.Time{}, true},
+		{"date only", "2030-01-02", time.Time{}, true},
+		{"empty", "", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseGenesisTime(tc.input, now)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.want.Equal(got), "got %s, want %s", got, tc.want)
+			require.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			"replace leaf",
+			`{"params":{"voting_period":"172800s","quorum":"0.4"}}`,
+			`{"params":{"voting_period":"600s"}}`,
+			`{"params":{"voting_period":"600s","quorum":"0.4"}}`,
+			false,
+		},
+		{
+			"add key",
+			`{"params":{"a":1}}`,
+			`{"params":{"b":2},"other":true}`,
+			`{"params":{"a":1,"b":2},"other":true}`,
+			false,
+		},
+		{
+			"array replaced",
+			`{"list":[1,2,3]}`,
+			`{"list":[4]}`,
+			`{"list":[4]}`,
+			false,
+		},
+		{
+			"object replaces scalar",
+			`{"a":"x"}`,
+			`{"a":{"b":1}}`,
+			`{"a":{"b":1}}`,
+			false,
+		},
+		{
+			"scalar replaces object",
+			`{"a":{"b":1}}`,
+			`{"a":"x"}`,
+			`{"a":"x"}`,
+			false,
+		},
+		{
+			"null patch value",
+			`{"a":{"b":1}}`,
+			`{"a":null}`,
+			`{"a":null}`,
+			false,
+		},
+		{
+			"non object patch",
+			`{"a":1}`,
+			`[1]`,
+			`[1]`,
+			false,
+		},
+		{
+			"invalid patch",
+			`{"a":1}`,
+			`{"a":`,
+			"",
+			true,
+		},
+		{
+			"invalid patch value",
+			`{"a":{"b":1}}`,
+			`{"a":{"b":}}`,
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mergeJSON(json.RawMessage(tc.base), json.RawMessage(tc.patch))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
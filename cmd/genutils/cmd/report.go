@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const flagReport = "report"
+
+// ChangeReport lists what a command changed in a genesis file, for reviewers
+// to sign off on the new genesis without reading it whole.
+type ChangeReport struct {
+	Command           string          `json:"command"`
+	Input             string          `json:"input"`
+	Output            string          `json:"output"`
+	Header            []FieldChange   `json:"header"`
+	AccountsAdded     []string        `json:"accounts_added"`
+	AccountsRemoved   []string        `json:"accounts_removed"`
+	Supply            []DenomDelta    `json:"supply"`
+	ValidatorsCreated []string        `json:"validators_created"`
+	ValidatorsRemoved []string        `json:"validators_removed"`
+	Delegations       DelegationsDiff `json:"delegations"`
+	Params            []FieldChange   `json:"params"`
+}
+
+// addReportFlag registers the --report flag read by writeChangeReport.
+func addReportFlag(cmd *cobra.Command) {
+	cmd.Flags().String(flagReport, "", "Write a report of the changes to this file, as Markdown if it ends with .md and as JSON otherwise")
+}
+
+// writeChangeReport writes the changes from before to after to the file given
+// with --report, if any. input and output are the genesis files read and
+// written by cmd.
+func writeChangeReport(cmd *cobra.Command, before, after *genesis.Genesis, input, output string) error {
+	path, err := cmd.Flags().GetString(flagReport)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	diff, err := diffGenesis(before, after)
+	if err != nil {
+		return err
+	}
+	report := ChangeReport{
+		Command:           cmd.CommandPath(),
+		Input:             input,
+		Output:            output,
+		Header:            diff.Header,
+		AccountsAdded:     diff.Accounts.Added,
+		AccountsRemoved:   diff.Accounts.Removed,
+		Supply:            diff.Supply,
+		ValidatorsCreated: diff.Validators.Added,
+		ValidatorsRemoved: diff.Validators.Removed,
+		Delegations:       diff.Delegations,
+		Params:            diff.Params,
+	}
+
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		report.writeMarkdown(&buf)
+	default:
+		bz, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal change report: %w", err)
+		}
+		buf.Write(bz)
+		buf.WriteByte('\n')
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write change report: %w", err)
+	}
+	return nil
+}
+
+// writeMarkdown writes the report as a Markdown document to w.
+func (r ChangeReport) writeMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# Genesis change report\n\n")
+	fmt.Fprintf(w, "`%s` wrote `%s` from `%s`.\n", r.Command, r.Output, r.Input)
+
+	fmt.Fprintf(w, "\n## Header\n\n")
+	writeMarkdownFields(w, r.Header)
+
+	fmt.Fprintf(w, "\n## Accounts\n\n")
+	fmt.Fprintf(w, "%d added, %d removed.\n", len(r.AccountsAdded), len(r.AccountsRemoved))
+	writeMarkdownList(w, "added", r.AccountsAdded)
+	writeMarkdownList(w, "removed", r.AccountsRemoved)
+
+	fmt.Fprintf(w, "\n## Supply\n\n")
+	if len(r.Supply) == 0 {
+		fmt.Fprintln(w, "No change.")
+	} else {
+		fmt.Fprintln(w, "| Denom | Old | New | Delta |")
+		fmt.Fprintln(w, "| --- | ---: | ---: | ---: |")
+		for _, delta := range r.Supply {
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", delta.Denom, delta.Old, delta.New, signedInt(delta.Delta()))
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Validators\n\n")
+	fmt.Fprintf(w, "%d created, %d removed.\n", len(r.ValidatorsCreated), len(r.ValidatorsRemoved))
+	writeMarkdownList(w, "created", r.ValidatorsCreated)
+	writeMarkdownList(w, "removed", r.ValidatorsRemoved)
+
+	fmt.Fprintf(w, "\n## Delegations\n\n")
+	fmt.Fprintf(w, "%d rewritten: %d added, %d removed, %d with changed shares.\n",
+		r.Delegations.Rewritten(), r.Delegations.Added, r.Delegations.Removed, r.Delegations.Changed)
+
+	fmt.Fprintf(w, "\n## Params\n\n")
+	writeMarkdownFields(w, r.Params)
+}
+
+// writeMarkdownFields writes fields as a Markdown table.
+func writeMarkdownFields(w io.Writer, fields []FieldChange) {
+	if len(fields) == 0 {
+		fmt.Fprintln(w, "No change.")
+		return
+	}
+	fmt.Fprintln(w, "| Field | Old | New |")
+	fmt.Fprintln(w, "| --- | --- | --- |")
+	for _, f := range fields {
+		fmt.Fprintf(w, "| %s | `%s` | `%s` |\n", f.Field, f.Old, f.New)
+	}
+}
+
+// writeMarkdownList writes items as a Markdown list, prefixing each of them
+// with verb.
+func writeMarkdownList(w io.Writer, verb string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	for _, item := range items {
+		fmt.Fprintf(w, "- %s `%s`\n", verb, item)
+	}
+}
@@ -16,7 +16,6 @@ import (
 	"github.com/cosmos/cosmos-sdk/client/keys"
 	"github.com/cosmos/cosmos-sdk/client/rpc"
 	"github.com/cosmos/cosmos-sdk/server"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/auth/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"github.com/cosmos/cosmos-sdk/x/crisis"
@@ -29,9 +28,6 @@ var ChainID string
 // NewRootCmd creates a new root command for simd. It is called once in the
 // main function.
 func NewRootCmd() (*cobra.Command, params.EncodingConfig) {
-	// Set config for prefixes
-	app.SetConfig()
-
 	encodingConfig := app.MakeEncodingConfig()
 	initClientCtx := client.Context{}.
 		WithCodec(encodingConfig.Marshaler).
@@ -40,13 +36,21 @@ func NewRootCmd() (*cobra.Command, params.EncodingConfig) {
 		WithLegacyAmino(encodingConfig.Amino).
 		WithInput(os.Stdin).
 		WithAccountRetriever(types.AccountRetriever{}).
-		WithHomeDir(app.DefaultNodeHome).
 		WithViper("")
 
 	rootCmd := &cobra.Command{
-		Use:   "bitsongd",
-		Short: "BitSong Network",
+		Use:   "genutils",
+		Short: "Genesis file utilities for Cosmos SDK chains",
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			// the chain profile sets the SDK config and the default home, so
+			// it is applied before anything reads them
+			if err := applyChainProfile(cmd); err != nil {
+				return err
+			}
+
+			initClientCtx := initClientCtx.
+				WithHomeDir(app.DefaultNodeHome).
+				WithChainID(app.ActiveProfile.ChainID)
 			initClientCtx, err := client.ReadPersistentCommandFlags(initClientCtx, cmd.Flags())
 			if err != nil {
 				return err
@@ -64,15 +68,14 @@ func NewRootCmd() (*cobra.Command, params.EncodingConfig) {
 		},
 	}
 
+	rootCmd.PersistentFlags().String(flagChainProfile, "", "Name of the chain profile, or path to a YAML or JSON chain profile file (default profile of "+defaultChainProfilesPath()+", or bitsong)")
+
 	initRootCmd(rootCmd, encodingConfig)
 
 	return rootCmd, encodingConfig
 }
 
 func initRootCmd(rootCmd *cobra.Command, encodingConfig params.EncodingConfig) {
-	cfg := sdk.GetConfig()
-	cfg.Seal()
-
 	rootCmd.AddCommand(
 		genutilcli.InitCmd(simapp.ModuleBasics, simapp.DefaultNodeHome),
 		genutilcli.CollectGenTxsCmd(banktypes.GenesisBalancesIterator{}, simapp.DefaultNodeHome),
@@ -80,7 +83,20 @@ func initRootCmd(rootCmd *cobra.Command, encodingConfig params.EncodingConfig) {
 		genutilcli.GenTxCmd(simapp.ModuleBasics, encodingConfig.TxConfig, banktypes.GenesisBalancesIterator{}, simapp.DefaultNodeHome),
 		genutilcli.ValidateGenesisCmd(simapp.ModuleBasics),
 		AddGenesisAccountCmd(app.DefaultNodeHome),
+		AddGenesisAccountsBulkCmd(app.DefaultNodeHome),
+		RemoveGenesisAccountCmd(app.DefaultNodeHome),
+		UpdateGenesisAccountCmd(app.DefaultNodeHome),
 		ExportUpgradedGenesisCmd(),
+		SwapConsensusKeysCmd(),
+		CheckInvariantsCmd(),
+		VerifyGenesisCmd(),
+		ExportStakedSnapshotCmd(),
+		ComputeAirdropCmd(),
+		MerkleAirdropCmd(),
+		RecomputeSupplyCmd(),
+		ConvertPrefixCmd(),
+		DiffGenesisCmd(),
+		TransformCmd(),
 		tmcli.NewCompletionCmd(rootCmd, true),
 		debug.Cmd(),
 		config.Cmd(),
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const (
+	flagIncludeUnbonding     = "include-unbonding"
+	flagIncludeRedelegations = "include-redelegations"
+	flagPrices               = "prices"
+)
+
+// ExportStakedSnapshotCmd returns export-staked-snapshot cobra Command.
+func ExportStakedSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-staked-snapshot [input-genesis-file] [output-snapshot-file]",
+		Short: "Export the staked balance of every address from a genesis export",
+		Long: `Export the staked balance of every address from a genesis export.
+
+Delegation shares are converted to tokens with the exchange rate of their
+validator. The balance of unbonding delegation entries is added unless
+--include-unbonding=false is given. Stake that is currently redelegated is
+part of the destination delegation; with --include-redelegations=false it is
+subtracted from the staked amount instead.
+
+With --prices, the usd_value of every account is computed from its staked
+amount and liquid balances using a price table, given either as a CSV file
+with denom,price,decimals lines or as a YAML/JSON list of objects with the
+same fields. Accounts holding only liquid balances are then included as well.
+Module accounts are never included.
+
+Example:
+	genutils export-staked-snapshot bitsong_export.json snapshot.json
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			includeUnbonding, err := cmd.Flags().GetBool(flagIncludeUnbonding)
+			if err != nil {
+				return err
+			}
+			includeRedelegations, err := cmd.Flags().GetBool(flagIncludeRedelegations)
+			if err != nil {
+				return err
+			}
+
+			pricesPath, err := cmd.Flags().GetString(flagPrices)
+			if err != nil {
+				return err
+			}
+
+			stakingGenesis, balances, moduleAccounts, err := readSnapshotState(clientCtx.Codec, args[0], pricesPath != "")
+			if err != nil {
+				return err
+			}
+
+			snapshot, err := deriveStakedSnapshot(stakingGenesis, includeUnbonding, includeRedelegations)
+			if err != nil {
+				return err
+			}
+
+			if pricesPath != "" {
+				prices, err := loadPriceTable(pricesPath)
+				if err != nil {
+					return err
+				}
+
+				valueSnapshot(&snapshot, balances, stakingGenesis.Params.BondDenom, prices, moduleAccounts)
+			}
+
+			return writeJSONFile(args[1], snapshot)
+		},
+	}
+
+	cmd.Flags().Bool(flagIncludeUnbonding, true, "Count the balance of unbonding delegations as staked")
+	cmd.Flags().Bool(flagIncludeRedelegations, true, "Count stake that is currently being redelegated as staked")
+	cmd.Flags().String(flagPrices, "", "CSV, YAML or JSON price table used to compute the USD value of each account")
+
+	return cmd
+}
+
+// deriveStakedSnapshot computes the staked amount of every delegator.
+func deriveStakedSnapshot(stakingGenesis stakingtypes.GenesisState, includeUnbonding, includeRedelegations bool) (DeriveSnapshotStaked, error) {
+	validators := make(map[string]stakingtypes.Validator, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = val
+	}
+
+	staked := make(map[string]sdk.Dec)
+	addStake := func(addr string, amt sdk.Dec) {
+		if total, ok := staked[addr]; ok {
+			staked[addr] = total.Add(amt)
+			return
+		}
+		staked[addr] = amt
+	}
+
+	for _, del := range stakingGenesis.Delegations {
+		val, ok := validators[del.ValidatorAddress]
+		if !ok {
+			return DeriveSnapshotStaked{}, fmt.Errorf("delegation of %s references unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+		}
+		if val.DelegatorShares.IsZero() {
+			return DeriveSnapshotStaked{}, fmt.Errorf("delegation of %s references validator %s which has no delegator shares", del.DelegatorAddress, del.ValidatorAddress)
+		}
+		addStake(del.DelegatorAddress, val.TokensFromShares(del.Shares))
+	}
+
+	if includeUnbonding {
+		for _, ubd := range stakingGenesis.UnbondingDelegations {
+			for _, entry := range ubd.Entries {
+				addStake(ubd.DelegatorAddress, entry.Balance.ToDec())
+			}
+		}
+	}
+
+	if !includeRedelegations {
+		for _, red := range stakingGenesis.Redelegations {
+			val, ok := validators[red.ValidatorDstAddress]
+			if !ok {
+				return DeriveSnapshotStaked{}, fmt.Errorf("redelegation of %s references unknown validator %s", red.DelegatorAddress, red.ValidatorDstAddress)
+			}
+			if val.DelegatorShares.IsZero() {
+				return DeriveSnapshotStaked{}, fmt.Errorf("redelegation of %s references validator %s which has no delegator shares", red.DelegatorAddress, red.ValidatorDstAddress)
+			}
+			for _, entry := range red.Entries {
+				addStake(red.DelegatorAddress, val.TokensFromShares(entry.SharesDst).Neg())
+			}
+		}
+	}
+
+	snapshot := DeriveSnapshotStaked{Accounts: []StakedAccount{}}
+	for addr, amt := range staked {
+		amount := amt.TruncateInt()
+		if !amount.IsPositive() {
+			continue
+		}
+		snapshot.Accounts = append(snapshot.Accounts, StakedAccount{
+			Address:  addr,
+			Staked:   amount,
+			UsdValue: sdk.ZeroInt(),
+		})
+	}
+	sort.Slice(snapshot.Accounts, func(i, j int) bool {
+		return snapshot.Accounts[i].Address < snapshot.Accounts[j].Address
+	})
+	snapshot.NumberAccounts = uint64(len(snapshot.Accounts))
+
+	return snapshot, nil
+}
+
+// readSnapshotState streams the staking state out of the genesis file at path.
+// With withBalances, the bank balances and the module account addresses are
+// read as well. Delegations, balances and accounts are decoded one at a time
+// and the other modules are skipped.
+func readSnapshotState(cdc codec.Codec, path string, withBalances bool) (stakingtypes.GenesisState, []banktypes.Balance, map[string]bool, error) {
+	var (
+		stakingGenesis stakingtypes.GenesisState
+		delegations    []stakingtypes.Delegation
+		balances       []banktypes.Balance
+		moduleAccounts = make(map[string]bool)
+	)
+
+	v := genesis.Visitor{
+		Modules: map[string]bool{stakingtypes.ModuleName: true},
+		OnModule: func(_ string, state json.RawMessage) error {
+			if err := cdc.UnmarshalJSON(state, &stakingGenesis); err != nil {
+				return fmt.Errorf("failed to unmarshal staking genesis state: %w", err)
+			}
+			return nil
+		},
+		Arrays: map[string]map[string]func(json.RawMessage) error{
+			stakingtypes.ModuleName: {
+				"delegations": func(raw json.RawMessage) error {
+					var del stakingtypes.Delegation
+					if err := cdc.UnmarshalJSON(raw, &del); err != nil {
+						return fmt.Errorf("failed to unmarshal delegation: %w", err)
+					}
+					delegations = append(delegations, del)
+					return nil
+				},
+			},
+		},
+	}
+
+	if withBalances {
+		v.Arrays[banktypes.ModuleName] = map[string]func(json.RawMessage) error{
+			"balances": func(raw json.RawMessage) error {
+				var balance banktypes.Balance
+				if err := cdc.UnmarshalJSON(raw, &balance); err != nil {
+					return fmt.Errorf("failed to unmarshal balance: %w", err)
+				}
+				balances = append(balances, balance)
+				return nil
+			},
+		}
+		v.Arrays[authtypes.ModuleName] = map[string]func(json.RawMessage) error{
+			"accounts": func(raw json.RawMessage) error {
+				var acc authtypes.GenesisAccount
+				if err := cdc.UnmarshalInterfaceJSON(raw, &acc); err != nil {
+					return fmt.Errorf("failed to unmarshal account: %w", err)
+				}
+				if _, ok := acc.(authtypes.ModuleAccountI); ok {
+					moduleAccounts[acc.GetAddress().String()] = true
+				}
+				return nil
+			},
+		}
+	}
+
+	if _, err := genesis.Stream(path, v); err != nil {
+		return stakingGenesis, nil, nil, err
+	}
+	stakingGenesis.Delegations = delegations
+
+	return stakingGenesis, balances, moduleAccounts, nil
+}
+
+// writeJSONFile atomically writes v as indented JSON to path.
+func writeJSONFile(path string, v interface{}) error {
+	bz, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+
+	return genesis.WriteFileAtomic(path, false, func(w io.Writer) error {
+		_, err := w.Write(bz)
+		return err
+	})
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveStakedSnapshot(t *testing.T) {
+	validator := func(operator string, tokens, shares int64) stakingtypes.Validator {
+		return stakingtypes.Validator{
+			OperatorAddress: operator,
+			Tokens:          sdk.NewInt(tokens),
+			DelegatorShares: sdk.NewDec(shares),
+		}
+	}
+	delegation := func(delegator, validator string, shares int64) stakingtypes.Delegation {
+		return stakingtypes.Delegation{DelegatorAddress: delegator, ValidatorAddress: validator, Shares: sdk.NewDec(shares)}
+	}
+	redelegation := func(delegator, dst string, shares int64) stakingtypes.Redelegation {
+		return stakingtypes.Redelegation{
+			DelegatorAddress:    delegator,
+			ValidatorSrcAddress: "valA",
+			ValidatorDstAddress: dst,
+			Entries: []stakingtypes.RedelegationEntry{
+				stakingtypes.NewRedelegationEntry(1, time.Time{}, sdk.NewInt(shares), sdk.NewDec(shares)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		state         stakingtypes.GenesisState
+		want          map[string]int64
+		wantErrSubstr string
+	}{
+		{
+			name: "tokens from shares",
+			state: stakingtypes.GenesisState{
+				Validators: []stakingtypes.Validator{validator("valA", 100, 100), validator("valB", 50, 100)},
+				Delegations: []stakingtypes.Delegation{
+					delegation("del1", "valA", 40),
+					delegation("del1", "valB", 20),
+					delegation("del2", "valB", 80),
+				},
+			},
+			want: map[string]int64{"del1": 50, "del2": 40},
+		},
+		{
+			name: "redelegated stake is excluded",
+			state: stakingtypes.GenesisState{
+				Validators:    []stakingtypes.Validator{validator("valA", 100, 100), validator("valB", 100, 100)},
+				Delegations:   []stakingtypes.Delegation{delegation("del1", "valB", 30)},
+				Redelegations: []stakingtypes.Redelegation{redelegation("del1", "valB", 10)},
+			},
+			want: map[string]int64{"del1": 20},
+		},
+		{
+			name: "validator without shares",
+			state: stakingtypes.GenesisState{
+				Validators:  []stakingtypes.Validator{validator("valA", 0, 0)},
+				Delegations: []stakingtypes.Delegation{delegation("del1", "valA", 10)},
+			},
+			wantErrSubstr: "no delegator shares",
+		},
+		{
+			name: "redelegation to validator without shares",
+			state: stakingtypes.GenesisState{
+				Validators:    []stakingtypes.Validator{validator("valA", 100, 100), validator("valB", 0, 0)},
+				Redelegations: []stakingtypes.Redelegation{redelegation("del1", "valB", 10)},
+			},
+			wantErrSubstr: "no delegator shares",
+		},
+		{
+			name: "unknown validator",
+			state: stakingtypes.GenesisState{
+				Delegations: []stakingtypes.Delegation{delegation("del1", "valA", 10)},
+			},
+			wantErrSubstr: "unknown validator",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			snapshot, err := deriveStakedSnapshot(tc.state, false, false)
+			if tc.wantErrSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.wantErrSubstr)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make(map[string]int64, len(snapshot.Accounts))
+			for _, acc := range snapshot.Accounts {
+				got[acc.Address] = acc.Staked.Int64()
+			}
+			require.Equal(t, tc.want, got)
+			require.Equal(t, uint64(len(tc.want)), snapshot.NumberAccounts)
+		})
+	}
+}
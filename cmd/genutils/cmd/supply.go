@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const flagNoSupplyFix = "no-supply-fix"
+
+// RecomputeSupplyCmd returns recompute-supply cobra Command.
+func RecomputeSupplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recompute-supply [input-genesis-file] [output-genesis-file]",
+		Short: "Set the bank supply to the sum of all balances",
+		Long: `Set the bank supply of a genesis file to the exact sum of all balances, per
+denom. The commands that change balances update the supply by the amounts they
+add or remove, then do the same unless --no-supply-fix is given.
+
+Example:
+	genutils recompute-supply genesis.json genesis.json
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+			before := g.Clone()
+
+			old, supply, err := reconcileSupply(g)
+			if err != nil {
+				return err
+			}
+			printSupplyChange(cmd, old, supply)
+
+			if err := exportGenesisFile(cmd, g, args[1]); err != nil {
+				return err
+			}
+
+			return writeChangeReport(cmd, before, g, args[0], args[1])
+		},
+	}
+
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// addSupplyFixFlag registers the --no-supply-fix flag read by fixSupply.
+func addSupplyFixFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(flagNoSupplyFix, false, "Do not recompute the bank supply from the sum of all balances")
+}
+
+// fixSupply reconciles the bank supply of g unless --no-supply-fix is given.
+func fixSupply(cmd *cobra.Command, g *genesis.Genesis) error {
+	noSupplyFix, err := cmd.Flags().GetBool(flagNoSupplyFix)
+	if err != nil {
+		return err
+	}
+	if noSupplyFix {
+		return nil
+	}
+
+	old, supply, err := reconcileSupply(g)
+	if err != nil {
+		return err
+	}
+	printSupplyChange(cmd, old, supply)
+	return nil
+}
+
+// adjustSupply adds the added coins to the supply of bankGenesis and subtracts
+// the removed ones. An empty supply is left empty since it is then computed at
+// chain initialization.
+func adjustSupply(bankGenesis *banktypes.GenesisState, added, removed sdk.Coins) error {
+	if bankGenesis.Supply.Empty() {
+		return nil
+	}
+	supply, negative := bankGenesis.Supply.Add(added...).SafeSub(removed)
+	if negative {
+		return fmt.Errorf("removing %s from supply %s would make it negative", removed, bankGenesis.Supply)
+	}
+	bankGenesis.Supply = supply
+	return nil
+}
+
+// reconcileSupply sets the bank supply of g to the sum of all balances and
+// returns the previous and the new supply.
+func reconcileSupply(g *genesis.Genesis) (sdk.Coins, sdk.Coins, error) {
+	bankGenesis, err := g.Bank()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	old := bankGenesis.Supply
+	supply := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		supply = supply.Add(balance.Coins...)
+	}
+	bankGenesis.Supply = supply
+
+	if err := g.SetBank(bankGenesis); err != nil {
+		return nil, nil, err
+	}
+
+	return old, supply, nil
+}
+
+// printSupplyChange prints the denoms whose supply changed.
+func printSupplyChange(cmd *cobra.Command, old, supply sdk.Coins) {
+	for _, denom := range unionDenoms(old, supply) {
+		before, after := old.AmountOf(denom), supply.AmountOf(denom)
+		if !before.Equal(after) {
+			cmd.Printf("supply of %s: %s -> %s\n", denom, before, after)
+		}
+	}
+}
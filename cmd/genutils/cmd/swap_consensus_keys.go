@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+// SwapConsensusKeysCmd returns swap-consensus-keys cobra Command.
+func SwapConsensusKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "swap-consensus-keys [input-genesis-file] [key-mapping-file] [output-genesis-file]",
+		Short: "Replace the consensus pubkeys of existing validators",
+		Long: `Replace the consensus pubkeys of existing validators while keeping the rest of
+the validator set, delegations, commissions and rewards untouched.
+
+The mapping file is a YAML or JSON object from operator address to the new
+ed25519 consensus pubkey, given either as a pubkey object or as the base64
+encoded key. Slashing signing infos and missed blocks, the previous proposer
+and the Tendermint genesis validators are moved to the new consensus addresses.
+
+Example:
+	genutils swap-consensus-keys bitsong_export.json keys.yaml new-bitsong-genesis.json
+
+keys.yaml:
+	bitsongvaloper13m350fvnk3s6y5n8ugxhmka277r0t7cw5rl49r: Dst4aT7mWIUriAO5IrGAxMoLh+ratiG92DHCOSZ8rAo=
+`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			keys, err := loadConsensusKeyMapping(clientCtx, args[1])
+			if err != nil {
+				return err
+			}
+
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+			before := g.Clone()
+
+			if err := swapConsensusKeys(g, keys); err != nil {
+				return err
+			}
+
+			if err := exportGenesisFile(cmd, g, args[2]); err != nil {
+				return err
+			}
+
+			return writeChangeReport(cmd, before, g, args[0], args[2])
+		},
+	}
+
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// loadConsensusKeyMapping reads a mapping of operator address to ed25519
+// consensus pubkey.
+func loadConsensusKeyMapping(clientCtx client.Context, path string) (map[string]cryptotypes.PubKey, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key mapping: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := unmarshalYAMLOrJSON(path, bz, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse key mapping %s: %w", path, err)
+	}
+
+	keys := make(map[string]cryptotypes.PubKey, len(raw))
+	seen := make(map[string]string, len(raw))
+	for operator, value := range raw {
+		if _, err := sdk.ValAddressFromBech32(operator); err != nil {
+			return nil, fmt.Errorf("invalid operator %s: %w", operator, err)
+		}
+
+		var pubKey cryptotypes.PubKey
+		var encoded string
+		if err := json.Unmarshal(value, &encoded); err == nil {
+			keyBz, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pubkey of %s: %w", operator, err)
+			}
+			if len(keyBz) != ed25519.PubKeySize {
+				return nil, fmt.Errorf("invalid pubkey of %s: expected %d bytes, got %d", operator, ed25519.PubKeySize, len(keyBz))
+			}
+			pubKey = &ed25519.PubKey{Key: keyBz}
+		} else if err := clientCtx.Codec.UnmarshalInterfaceJSON(value, &pubKey); err != nil {
+			return nil, fmt.Errorf("invalid pubkey of %s: %w", operator, err)
+		}
+
+		if _, ok := pubKey.(*ed25519.PubKey); !ok {
+			return nil, fmt.Errorf("pubkey of %s is a %s key, expected ed25519", operator, pubKey.Type())
+		}
+
+		consAddr := sdk.ConsAddress(pubKey.Address()).String()
+		if other, ok := seen[consAddr]; ok {
+			return nil, fmt.Errorf("%s and %s are mapped to the same pubkey", other, operator)
+		}
+		seen[consAddr] = operator
+		keys[operator] = pubKey
+	}
+
+	return keys, nil
+}
+
+// swapConsensusKeys sets the consensus pubkey of the validators in keys and
+// re-keys every consensus address indexed state accordingly.
+func swapConsensusKeys(g *genesis.Genesis, keys map[string]cryptotypes.PubKey) error {
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return err
+	}
+
+	// old consensus address -> new consensus pubkey
+	swaps := make(map[string]cryptotypes.PubKey, len(keys))
+	existing := make(map[string]string, len(stakingGenesis.Validators))
+	for i, val := range stakingGenesis.Validators {
+		consAddr, err := val.GetConsAddr()
+		if err != nil {
+			return fmt.Errorf("failed to get consensus address of %s: %w", val.OperatorAddress, err)
+		}
+		existing[consAddr.String()] = val.OperatorAddress
+
+		pubKey, ok := keys[val.OperatorAddress]
+		if !ok {
+			continue
+		}
+
+		pkAny, err := codectypes.NewAnyWithValue(pubKey)
+		if err != nil {
+			return err
+		}
+		stakingGenesis.Validators[i].ConsensusPubkey = pkAny
+		swaps[consAddr.String()] = pubKey
+	}
+
+	for operator, pubKey := range keys {
+		found := false
+		for _, val := range stakingGenesis.Validators {
+			if val.OperatorAddress == operator {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("validator %s not found in staking genesis state", operator)
+		}
+
+		consAddr := sdk.ConsAddress(pubKey.Address()).String()
+		if other, ok := existing[consAddr]; ok && other != operator {
+			if _, swapped := swaps[consAddr]; !swapped {
+				return fmt.Errorf("pubkey of %s is already used by validator %s", operator, other)
+			}
+		}
+	}
+
+	if err := g.SetStaking(stakingGenesis); err != nil {
+		return err
+	}
+
+	newConsAddr := func(addr string) (string, bool) {
+		pubKey, ok := swaps[addr]
+		if !ok {
+			return addr, false
+		}
+		return sdk.ConsAddress(pubKey.Address()).String(), true
+	}
+
+	slashingGenesis, err := g.Slashing()
+	if err != nil {
+		return err
+	}
+	for i, info := range slashingGenesis.SigningInfos {
+		if addr, ok := newConsAddr(info.Address); ok {
+			slashingGenesis.SigningInfos[i].Address = addr
+			slashingGenesis.SigningInfos[i].ValidatorSigningInfo.Address = addr
+		}
+	}
+	for i, missed := range slashingGenesis.MissedBlocks {
+		if addr, ok := newConsAddr(missed.Address); ok {
+			slashingGenesis.MissedBlocks[i].Address = addr
+		}
+	}
+	sort.SliceStable(slashingGenesis.SigningInfos, func(i, j int) bool {
+		return slashingGenesis.SigningInfos[i].Address < slashingGenesis.SigningInfos[j].Address
+	})
+	sort.SliceStable(slashingGenesis.MissedBlocks, func(i, j int) bool {
+		return slashingGenesis.MissedBlocks[i].Address < slashingGenesis.MissedBlocks[j].Address
+	})
+	if err := g.SetSlashing(slashingGenesis); err != nil {
+		return err
+	}
+
+	distrGenesis, err := g.Distribution()
+	if err != nil {
+		return err
+	}
+	distrGenesis.PreviousProposer, _ = newConsAddr(distrGenesis.PreviousProposer)
+	if err := g.SetDistribution(distrGenesis); err != nil {
+		return err
+	}
+
+	for i, val := range g.Doc.Validators {
+		pubKey, ok := swaps[sdk.ConsAddress(val.Address).String()]
+		if !ok {
+			continue
+		}
+		tmPubKey, err := cryptocodec.ToTmPubKeyInterface(pubKey)
+		if err != nil {
+			return err
+		}
+		g.Doc.Validators[i].PubKey = tmPubKey
+		g.Doc.Validators[i].Address = tmPubKey.Address()
+	}
+
+	return nil
+}
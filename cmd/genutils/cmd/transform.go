@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/spf13/cobra"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+// builtinTransform is a transform that can be listed in a pipeline file.
+type builtinTransform interface {
+	genesis.Transform
+	// validate performs a stateless check of the transform parameters.
+	validate() error
+}
+
+// transformDefaults is implemented by the transforms having parameters with
+// default values, which are filled in before the transform is validated.
+type transformDefaults interface {
+	setDefaults()
+}
+
+// builtinTransforms are the transforms available in pipeline files, by name.
+var builtinTransforms = map[string]func() builtinTransform{
+	"set-chain-id":       func() builtinTransform { return &setChainIDTransform{} },
+	"fund-account":       func() builtinTransform { return &fundAccountTransform{} },
+	"add-validator":      func() builtinTransform { return &addValidatorTransform{} },
+	"shorten-gov":        func() builtinTransform { return &shortenGovTransform{} },
+	"reset-distribution": func() builtinTransform { return &resetDistributionTransform{} },
+	"override-params":    func() builtinTransform { return &overrideParamsTransform{} },
+	"shift-time":         func() builtinTransform { return &shiftTimeTransform{} },
+}
+
+// TransformCmd returns transform cobra Command.
+func TransformCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transform [input-genesis-file] [pipeline-file] [output-genesis-file]",
+		Short: "Apply a pipeline of transforms to a genesis file",
+		Long: fmt.Sprintf(`Apply the transforms listed in a YAML or JSON pipeline file to a genesis file,
+in order. Every transform is printed along with the changes it made. All the
+transforms are validated before the genesis file is read.
+
+Each transform is an object whose name field is one of %s,
+the other fields being the parameters of the transform:
+
+	transforms:
+	  - name: set-chain-id
+	    chain_id: bitsong-fork-1
+	  - name: fund-account
+	    address: bitsong13m350fvnk3s6y5n8ugxhmka277r0t7cw48ru47
+	    coins: 1000000000ubtsg
+	  - name: shorten-gov
+	    voting_period: 10m
+	    max_deposit_period: 10m
+
+Example:
+	genutils transform bitsong_export.json pipeline.yaml new-bitsong-genesis.json
+`, strings.Join(builtinTransformNames(), ", ")),
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			transforms, err := loadPipeline(args[1])
+			if err != nil {
+				return err
+			}
+
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+			before := g.Clone()
+			g.Log = cmd.OutOrStdout()
+
+			if err := g.Apply(transforms...); err != nil {
+				return err
+			}
+
+			if err := fixSupply(cmd, g); err != nil {
+				return err
+			}
+
+			if err := exportGenesisFile(cmd, g, args[2]); err != nil {
+				return err
+			}
+
+			return writeChangeReport(cmd, before, g, args[0], args[2])
+		},
+	}
+
+	addSupplyFixFlag(cmd)
+	addBackupFlag(cmd)
+	addReportFlag(cmd)
+
+	return cmd
+}
+
+// loadPipeline reads and validates the transforms of a YAML or JSON pipeline
+// file.
+func loadPipeline(path string) ([]genesis.Transform, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	var raw struct {
+		Transforms []map[string]json.RawMessage `json:"transforms"`
+	}
+	if err := unmarshalYAMLOrJSON(path, bz, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline %s: %w", path, err)
+	}
+	if len(raw.Transforms) == 0 {
+		return nil, fmt.Errorf("no transforms in %s", path)
+	}
+
+	transforms := make([]genesis.Transform, len(raw.Transforms))
+	for i, fields := range raw.Transforms {
+		if transforms[i], err = parseTransform(fields); err != nil {
+			return nil, fmt.Errorf("transform %d: %w", i+1, err)
+		}
+	}
+
+	return transforms, nil
+}
+
+// parseTransform builds the built-in transform named by the name field of
+// fields from its other fields. Unknown fields are refused.
+func parseTransform(fields map[string]json.RawMessage) (genesis.Transform, error) {
+	var name string
+	if err := json.Unmarshal(fields["name"], &name); err != nil || name == "" {
+		return nil, errors.New("missing name")
+	}
+	newTransform, ok := builtinTransforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %q", name)
+	}
+	delete(fields, "name")
+
+	params, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTransform()
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(t); err != nil {
+		return nil, fmt.Errorf("invalid %s parameters: %w", name, err)
+	}
+	if d, ok := t.(transformDefaults); ok {
+		d.setDefaults()
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s parameters: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// builtinTransformNames returns the sorted names of the built-in transforms.
+func builtinTransformNames() []string {
+	names := make([]string, 0, len(builtinTransforms))
+	for name := range builtinTransforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-btsg/genutils/app"
+)
+
+func TestAddValidatorTransformInitChain(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+
+	tests := []struct {
+		name string
+		// clearDocValidators empties the validators of the genesis doc
+		clearDocValidators bool
+		wantDocValidators  int
+	}{
+		{"genesis validators", false, 2},
+		{"no genesis validators", true, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := exportedTestGenesis(t)
+			if tc.clearDocValidators {
+				g.Doc.Validators = nil
+			}
+
+			pubKey := ed25519.GenPrivKey().PubKey()
+			pubKeyJSON, err := cdc.MarshalInterfaceJSON(pubKey)
+			require.NoError(t, err)
+			operator := sdk.ValAddress(pubKey.Address())
+
+			fields := map[string]json.RawMessage{
+				"name":     json.RawMessage(`"add-validator"`),
+				"owner":    json.RawMessage(`"` + sdk.AccAddress(operator).String() + `"`),
+				"operator": json.RawMessage(`"` + operator.String() + `"`),
+				"pubkey":   pubKeyJSON,
+				"moniker":  json.RawMessage(`"added"`),
+				"stake":    json.RawMessage(`"50000000` + sdk.DefaultBondDenom + `"`),
+			}
+			transform, err := parseTransform(fields)
+			require.NoError(t, err)
+			require.Equal(t, defaultCommissionRate, transform.(*addValidatorTransform).Commission.Rate)
+
+			require.NoError(t, transform.Apply(g))
+			require.Len(t, g.Doc.Validators, tc.wantDocValidators)
+
+			doc, err := g.GenesisDoc()
+			require.NoError(t, err)
+			// the exported distribution state of the fixture does not hold
+			// the records checked by the invariants
+			validators, err := dryRunInitChain(doc, true)
+			require.NoError(t, err)
+			require.Len(t, validators, 2)
+			require.Equal(t, operator.String(), validators[1].Operator)
+			require.Equal(t, int64(50), validators[1].Power)
+		})
+	}
+}
+
+func TestAddValidatorTransformValidate(t *testing.T) {
+	transform := &addValidatorTransform{
+		Owner:    sdk.AccAddress("owner-address-000001").String(),
+		Operator: sdk.ValAddress("operator-address-001").String(),
+		PubKey:   json.RawMessage(`{}`),
+		Moniker:  "added",
+		Stake:    "1" + sdk.DefaultBondDenom,
+	}
+
+	// validate leaves the defaults to setDefaults
+	require.Error(t, transform.validate())
+	require.Empty(t, transform.Commission.Rate)
+
+	transform.setDefaults()
+	require.NoError(t, transform.validate())
+}
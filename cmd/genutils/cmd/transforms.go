@@ -0,0 +1,732 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	authvesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/go-btsg/genutils/genesis"
+)
+
+// setChainIDTransform sets the chain id of the genesis doc.
+type setChainIDTransform struct {
+	ChainID string `json:"chain_id"`
+}
+
+func (t *setChainIDTransform) Name() string { return "set-chain-id" }
+
+func (t *setChainIDTransform) Describe() string {
+	return fmt.Sprintf("set the chain id to %s", t.ChainID)
+}
+
+func (t *setChainIDTransform) validate() error {
+	if t.ChainID == "" {
+		return errors.New("missing chain_id")
+	}
+	if len(t.ChainID) > tmtypes.MaxChainIDLen {
+		return fmt.Errorf("chain_id is longer than %d characters", tmtypes.MaxChainIDLen)
+	}
+	return nil
+}
+
+func (t *setChainIDTransform) Apply(g *genesis.Genesis) error {
+	g.Logf("chain_id: %s -> %s", g.Doc.ChainID, t.ChainID)
+	g.Doc.ChainID = t.ChainID
+	return nil
+}
+
+// fundAccountTransform adds coins to the balance of an account, creating the
+// account if needed.
+type fundAccountTransform struct {
+	Address string `json:"address"`
+	Coins   string `json:"coins"`
+}
+
+func (t *fundAccountTransform) Name() string { return "fund-account" }
+
+func (t *fundAccountTransform) Describe() string {
+	return fmt.Sprintf("add %s to %s", t.Coins, t.Address)
+}
+
+func (t *fundAccountTransform) validate() error {
+	if _, err := sdk.AccAddressFromBech32(t.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	coins, err := sdk.ParseCoinsNormalized(t.Coins)
+	if err != nil {
+		return fmt.Errorf("invalid coins: %w", err)
+	}
+	if coins.IsZero() {
+		return errors.New("coins must be positive")
+	}
+	return nil
+}
+
+func (t *fundAccountTransform) Apply(g *genesis.Genesis) error {
+	addr, err := sdk.AccAddressFromBech32(t.Address)
+	if err != nil {
+		return err
+	}
+	coins, err := sdk.ParseCoinsNormalized(t.Coins)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureGenesisAccount(g, addr); err != nil {
+		return err
+	}
+
+	bankGenesis, err := g.Bank()
+	if err != nil {
+		return err
+	}
+	if err := adjustSupply(&bankGenesis, coins, nil); err != nil {
+		return err
+	}
+	bankGenesis.Balances = addBalance(bankGenesis.Balances, banktypes.Balance{Address: t.Address, Coins: coins})
+	g.Logf("balance of %s: +%s = %s", t.Address, coins, balanceOf(bankGenesis.Balances, t.Address))
+
+	return g.SetBank(bankGenesis)
+}
+
+// addValidatorTransform adds a bonded validator with a self delegation of
+// stake, which is minted into the bonded pool.
+type addValidatorTransform struct {
+	Owner      string           `json:"owner"`
+	Operator   string           `json:"operator"`
+	PubKey     json.RawMessage  `json:"pubkey"`
+	Moniker    string           `json:"moniker"`
+	Stake      string           `json:"stake"`
+	Commission RecipeCommission `json:"commission"`
+}
+
+func (t *addValidatorTransform) Name() string { return "add-validator" }
+
+func (t *addValidatorTransform) Describe() string {
+	return fmt.Sprintf("add validator %s (%s) self delegating %s", t.Operator, t.Moniker, t.Stake)
+}
+
+func (t *addValidatorTransform) setDefaults() {
+	if t.Commission.Rate == "" {
+		t.Commission.Rate = defaultCommissionRate
+	}
+	if t.Commission.MaxRate == "" {
+		t.Commission.MaxRate = defaultCommissionMaxRate
+	}
+	if t.Commission.MaxChangeRate == "" {
+		t.Commission.MaxChangeRate = defaultCommissionMaxChangeRate
+	}
+}
+
+func (t *addValidatorTransform) validate() error {
+	if _, err := sdk.AccAddressFromBech32(t.Owner); err != nil {
+		return fmt.Errorf("invalid owner: %w", err)
+	}
+	if _, err := sdk.ValAddressFromBech32(t.Operator); err != nil {
+		return fmt.Errorf("invalid operator: %w", err)
+	}
+	if len(t.PubKey) == 0 {
+		return errors.New("missing pubkey")
+	}
+	if t.Moniker == "" {
+		return errors.New("missing moniker")
+	}
+	stake, err := sdk.ParseCoinNormalized(t.Stake)
+	if err != nil {
+		return fmt.Errorf("invalid stake: %w", err)
+	}
+	if !stake.IsPositive() {
+		return errors.New("stake must be positive")
+	}
+
+	_, err = t.Commission.toCommission()
+	return err
+}
+
+// Apply adds the validator to the staking state and its last validator
+// powers. For exported staking states, whose hooks are not run by InitChain,
+// the distribution records and the slashing signing info of the validator are
+// created as well.
+func (t *addValidatorTransform) Apply(g *genesis.Genesis) error {
+	owner, err := sdk.AccAddressFromBech32(t.Owner)
+	if err != nil {
+		return err
+	}
+	stake, err := sdk.ParseCoinNormalized(t.Stake)
+	if err != nil {
+		return err
+	}
+	commission, err := t.Commission.toCommission()
+	if err != nil {
+		return err
+	}
+	commission.UpdateTime = g.Doc.GenesisTime
+
+	var pubKey cryptotypes.PubKey
+	if err := g.Codec().UnmarshalInterfaceJSON(t.PubKey, &pubKey); err != nil {
+		return fmt.Errorf("failed to unmarshal pubkey of %s: %w", t.Operator, err)
+	}
+	pkAny, err := codectypes.NewAnyWithValue(pubKey)
+	if err != nil {
+		return err
+	}
+	consAddr := sdk.ConsAddress(pubKey.Address())
+
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return err
+	}
+	if stake.Denom != stakingGenesis.Params.BondDenom {
+		return fmt.Errorf("stake must be in the bond denom %s", stakingGenesis.Params.BondDenom)
+	}
+	for _, val := range stakingGenesis.Validators {
+		if val.OperatorAddress == t.Operator {
+			return fmt.Errorf("validator %s already exists", t.Operator)
+		}
+		if valConsAddr, err := val.GetConsAddr(); err == nil && valConsAddr.Equals(consAddr) {
+			return fmt.Errorf("pubkey is already used by validator %s", val.OperatorAddress)
+		}
+	}
+
+	val := stakingtypes.Validator{
+		OperatorAddress:   t.Operator,
+		ConsensusPubkey:   pkAny,
+		Status:            stakingtypes.Bonded,
+		Tokens:            stake.Amount,
+		DelegatorShares:   stake.Amount.ToDec(),
+		Description:       stakingtypes.NewDescription(t.Moniker, "", "", "", ""),
+		Commission:        commission,
+		MinSelfDelegation: sdk.NewInt(1),
+	}
+	power := val.ConsensusPower(sdk.DefaultPowerReduction)
+	if power == 0 {
+		return fmt.Errorf("stake %s is below one unit of consensus power", stake)
+	}
+
+	stakingGenesis.Validators = append(stakingGenesis.Validators, val)
+	stakingGenesis.Delegations = append(stakingGenesis.Delegations, stakingtypes.Delegation{
+		DelegatorAddress: t.Owner,
+		ValidatorAddress: t.Operator,
+		Shares:           val.DelegatorShares,
+	})
+	stakingGenesis.LastValidatorPowers = append(stakingGenesis.LastValidatorPowers, stakingtypes.LastValidatorPower{
+		Address: t.Operator,
+		Power:   power,
+	})
+	stakingGenesis.LastTotalPower = stakingGenesis.LastTotalPower.Add(sdk.NewInt(power))
+	if err := g.SetStaking(stakingGenesis); err != nil {
+		return err
+	}
+	g.Logf("created validator %s (%s) with power %d", t.Operator, consAddr, power)
+	g.Logf("created delegation of %s from %s", stake, t.Owner)
+
+	if err := ensureGenesisAccount(g, owner); err != nil {
+		return err
+	}
+
+	bankGenesis, err := g.Bank()
+	if err != nil {
+		return err
+	}
+	bondedPoolAddr := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+	if err := adjustSupply(&bankGenesis, sdk.NewCoins(stake), nil); err != nil {
+		return err
+	}
+	bankGenesis.Balances = addBalance(bankGenesis.Balances, banktypes.Balance{Address: bondedPoolAddr, Coins: sdk.NewCoins(stake)})
+	if err := g.SetBank(bankGenesis); err != nil {
+		return err
+	}
+	g.Logf("balance of the bonded pool: +%s", stake)
+
+	// InitChain requires the validators of the genesis doc, if any, to match
+	// the set returned by the staking module. When there are none, Tendermint
+	// takes the set returned by InitChain, which includes the new validator.
+	if len(g.Doc.Validators) == 0 {
+		g.Logf("left the genesis validators empty, InitChain returns %s", t.Moniker)
+	} else {
+		tmPubKey, err := cryptocodec.ToTmPubKeyInterface(pubKey)
+		if err != nil {
+			return err
+		}
+		g.Doc.Validators = append(g.Doc.Validators, tmtypes.GenesisValidator{
+			Address: tmPubKey.Address(),
+			PubKey:  tmPubKey,
+			Power:   power,
+			Name:    t.Moniker,
+		})
+		g.Logf("added %s to the genesis validators", t.Moniker)
+	}
+
+	if !stakingGenesis.Exported {
+		return nil
+	}
+
+	distrGenesis, err := g.Distribution()
+	if err != nil {
+		return err
+	}
+	addValidatorRewards(&distrGenesis, t.Operator, 1)
+	distrGenesis.DelegatorStartingInfos = append(distrGenesis.DelegatorStartingInfos, distrtypes.DelegatorStartingInfoRecord{
+		DelegatorAddress: t.Owner,
+		ValidatorAddress: t.Operator,
+		StartingInfo:     distrtypes.NewDelegatorStartingInfo(0, val.DelegatorShares, 0),
+	})
+	if err := g.SetDistribution(distrGenesis); err != nil {
+		return err
+	}
+	g.Logf("created the distribution records of %s", t.Operator)
+
+	slashingGenesis, err := g.Slashing()
+	if err != nil {
+		return err
+	}
+	slashingGenesis.SigningInfos = append(slashingGenesis.SigningInfos, slashingtypes.SigningInfo{
+		Address:              consAddr.String(),
+		ValidatorSigningInfo: slashingtypes.NewValidatorSigningInfo(consAddr, 0, 0, time.Unix(0, 0).UTC(), false, 0),
+	})
+	slashingGenesis.MissedBlocks = append(slashingGenesis.MissedBlocks, slashingtypes.ValidatorMissedBlocks{
+		Address:      consAddr.String(),
+		MissedBlocks: []slashingtypes.MissedBlock{},
+	})
+	if err := g.SetSlashing(slashingGenesis); err != nil {
+		return err
+	}
+	g.Logf("created the signing info of %s", consAddr)
+
+	return nil
+}
+
+// shortenGovTransform shortens the voting and deposit periods of gov, along
+// with the end times of the proposals that would otherwise end later.
+type shortenGovTransform struct {
+	VotingPeriod     string `json:"voting_period"`
+	MaxDepositPeriod string `json:"max_deposit_period"`
+}
+
+func (t *shortenGovTransform) Name() string { return "shorten-gov" }
+
+func (t *shortenGovTransform) Describe() string {
+	var parts []string
+	if t.VotingPeriod != "" {
+		parts = append(parts, "voting period to "+t.VotingPeriod)
+	}
+	if t.MaxDepositPeriod != "" {
+		parts = append(parts, "max deposit period to "+t.MaxDepositPeriod)
+	}
+	return "set the " + strings.Join(parts, " and the ")
+}
+
+func (t *shortenGovTransform) validate() error {
+	if t.VotingPeriod == "" && t.MaxDepositPeriod == "" {
+		return errors.New("missing voting_period or max_deposit_period")
+	}
+	if _, err := t.periods(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// periods returns the voting and the max deposit period, zero when not set.
+func (t *shortenGovTransform) periods() ([2]time.Duration, error) {
+	var periods [2]time.Duration
+	for i, s := range []string{t.VotingPeriod, t.MaxDepositPeriod} {
+		if s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return periods, fmt.Errorf("invalid period %q: %w", s, err)
+		}
+		if d <= 0 {
+			return periods, fmt.Errorf("period %q must be positive", s)
+		}
+		periods[i] = d
+	}
+	return periods, nil
+}
+
+func (t *shortenGovTransform) Apply(g *genesis.Genesis) error {
+	periods, err := t.periods()
+	if err != nil {
+		return err
+	}
+	votingPeriod, maxDepositPeriod := periods[0], periods[1]
+
+	govGenesis, err := g.Gov()
+	if err != nil {
+		return err
+	}
+
+	if votingPeriod != 0 {
+		g.Logf("voting_period: %s -> %s", govGenesis.VotingParams.VotingPeriod, votingPeriod)
+		govGenesis.VotingParams.VotingPeriod = votingPeriod
+	}
+	if maxDepositPeriod != 0 {
+		g.Logf("max_deposit_period: %s -> %s", govGenesis.DepositParams.MaxDepositPeriod, maxDepositPeriod)
+		govGenesis.DepositParams.MaxDepositPeriod = maxDepositPeriod
+	}
+
+	for i, proposal := range govGenesis.Proposals {
+		switch {
+		case proposal.Status == govtypes.StatusVotingPeriod && votingPeriod != 0:
+			if end := g.Doc.GenesisTime.Add(votingPeriod); proposal.VotingEndTime.After(end) {
+				govGenesis.Proposals[i].VotingEndTime = end
+				g.Logf("voting end time of proposal %d: %s -> %s", proposal.ProposalId, proposal.VotingEndTime, end)
+			}
+		case proposal.Status == govtypes.StatusDepositPeriod && maxDepositPeriod != 0:
+			if end := g.Doc.GenesisTime.Add(maxDepositPeriod); proposal.DepositEndTime.After(end) {
+				govGenesis.Proposals[i].DepositEndTime = end
+				g.Logf("deposit end time of proposal %d: %s -> %s", proposal.ProposalId, proposal.DepositEndTime, end)
+			}
+		}
+	}
+
+	return g.SetGov(govGenesis)
+}
+
+// resetDistributionTransform moves the outstanding rewards and commissions of
+// every validator to the community pool and restarts the reward accounting of
+// every validator and delegation from scratch.
+type resetDistributionTransform struct{}
+
+func (t *resetDistributionTransform) Name() string { return "reset-distribution" }
+
+func (t *resetDistributionTransform) Describe() string {
+	return "move the outstanding rewards to the community pool and reset the reward records"
+}
+
+func (t *resetDistributionTransform) validate() error { return nil }
+
+func (t *resetDistributionTransform) Apply(g *genesis.Genesis) error {
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return err
+	}
+	distrGenesis, err := g.Distribution()
+	if err != nil {
+		return err
+	}
+
+	var outstanding sdk.DecCoins
+	for _, rewards := range distrGenesis.OutstandingRewards {
+		outstanding = outstanding.Add(rewards.OutstandingRewards...)
+	}
+	if !outstanding.IsZero() {
+		distrGenesis.FeePool.CommunityPool = distrGenesis.FeePool.CommunityPool.Add(outstanding...)
+		g.Logf("community pool: +%s = %s", outstanding, distrGenesis.FeePool.CommunityPool)
+	}
+
+	validators := make(map[string]stakingtypes.Validator, len(stakingGenesis.Validators))
+	delegations := make(map[string]uint32, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = val
+	}
+	for _, del := range stakingGenesis.Delegations {
+		delegations[del.ValidatorAddress]++
+	}
+
+	g.Logf("removed %d historical rewards, %d current rewards, %d commissions and %d slash events",
+		len(distrGenesis.ValidatorHistoricalRewards), len(distrGenesis.ValidatorCurrentRewards),
+		len(distrGenesis.ValidatorAccumulatedCommissions), len(distrGenesis.ValidatorSlashEvents))
+	distrGenesis.OutstandingRewards = []distrtypes.ValidatorOutstandingRewardsRecord{}
+	distrGenesis.ValidatorAccumulatedCommissions = []distrtypes.ValidatorAccumulatedCommissionRecord{}
+	distrGenesis.ValidatorHistoricalRewards = []distrtypes.ValidatorHistoricalRewardsRecord{}
+	distrGenesis.ValidatorCurrentRewards = []distrtypes.ValidatorCurrentRewardsRecord{}
+	distrGenesis.ValidatorSlashEvents = []distrtypes.ValidatorSlashEventRecord{}
+	for _, val := range stakingGenesis.Validators {
+		addValidatorRewards(&distrGenesis, val.OperatorAddress, delegations[val.OperatorAddress])
+	}
+
+	distrGenesis.DelegatorStartingInfos = make([]distrtypes.DelegatorStartingInfoRecord, 0, len(stakingGenesis.Delegations))
+	for _, del := range stakingGenesis.Delegations {
+		val, ok := validators[del.ValidatorAddress]
+		if !ok {
+			return fmt.Errorf("delegation of %s references unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+		}
+		distrGenesis.DelegatorStartingInfos = append(distrGenesis.DelegatorStartingInfos, distrtypes.DelegatorStartingInfoRecord{
+			DelegatorAddress: del.DelegatorAddress,
+			ValidatorAddress: del.ValidatorAddress,
+			StartingInfo:     distrtypes.NewDelegatorStartingInfo(0, val.TokensFromShares(del.Shares), 0),
+		})
+	}
+	g.Logf("reset the records of %d validators and %d delegations", len(stakingGenesis.Validators), len(stakingGenesis.Delegations))
+
+	return g.SetDistribution(distrGenesis)
+}
+
+// overrideParamsTransform deep merges JSON objects into module states, like
+// the param_overrides of a fork recipe.
+type overrideParamsTransform struct {
+	Overrides map[string]json.RawMessage `json:"overrides"`
+}
+
+func (t *overrideParamsTransform) Name() string { return "override-params" }
+
+func (t *overrideParamsTransform) Describe() string {
+	return "override the state of " + strings.Join(t.modules(), ", ")
+}
+
+func (t *overrideParamsTransform) validate() error {
+	if len(t.Overrides) == 0 {
+		return errors.New("missing overrides")
+	}
+	return nil
+}
+
+func (t *overrideParamsTransform) Apply(g *genesis.Genesis) error {
+	if err := applyParamOverrides(g.AppState, t.Overrides); err != nil {
+		return err
+	}
+	for _, module := range t.modules() {
+		g.Logf("%s: merged %s", module, compactJSON(t.Overrides[module]))
+	}
+	return nil
+}
+
+func (t *overrideParamsTransform) modules() []string {
+	modules := make([]string, 0, len(t.Overrides))
+	for module := range t.Overrides {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// shiftTimeTransform moves the pending timestamps of the staking, auth, gov
+// and slashing states by a fixed delta, or by the time elapsed between the
+// export and the genesis time, so that they keep their distance to the start
+// of the chain.
+type shiftTimeTransform struct {
+	Delta string `json:"delta"`
+	From  string `json:"from"`
+}
+
+func (t *shiftTimeTransform) Name() string { return "shift-time" }
+
+func (t *shiftTimeTransform) Describe() string {
+	if t.Delta != "" {
+		return "shift the timestamps by " + t.Delta
+	}
+	return fmt.Sprintf("shift the timestamps from %s to the genesis time", t.From)
+}
+
+func (t *shiftTimeTransform) validate() error {
+	if (t.Delta == "") == (t.From == "") {
+		return errors.New("exactly one of delta and from must be set")
+	}
+	if t.Delta != "" {
+		if _, err := time.ParseDuration(t.Delta); err != nil {
+			return fmt.Errorf("invalid delta %q: %w", t.Delta, err)
+		}
+	}
+	if t.From != "" {
+		if _, err := time.Parse(time.RFC3339Nano, t.From); err != nil {
+			return fmt.Errorf("invalid from time %q: %w", t.From, err)
+		}
+	}
+	return nil
+}
+
+// delta returns the duration to add to every timestamp of g.
+func (t *shiftTimeTransform) delta(g *genesis.Genesis) (time.Duration, error) {
+	if t.Delta != "" {
+		return time.ParseDuration(t.Delta)
+	}
+	from, err := time.Parse(time.RFC3339Nano, t.From)
+	if err != nil {
+		return 0, err
+	}
+	return g.Doc.GenesisTime.Sub(from), nil
+}
+
+func (t *shiftTimeTransform) Apply(g *genesis.Genesis) error {
+	delta, err := t.delta(g)
+	if err != nil {
+		return err
+	}
+	g.Logf("delta: %s", delta)
+
+	shifts := []struct {
+		module string
+		shift  func(*genesis.Genesis, time.Duration) (int, error)
+	}{
+		{stakingtypes.ModuleName, shiftStakingTimes},
+		{authtypes.ModuleName, shiftVestingTimes},
+		{govtypes.ModuleName, shiftGovTimes},
+		{slashingtypes.ModuleName, shiftSlashingTimes},
+	}
+	for _, s := range shifts {
+		n, err := s.shift(g, delta)
+		if err != nil {
+			return err
+		}
+		g.Logf("%s: shifted %d entries", s.module, n)
+	}
+	return nil
+}
+
+// shiftStakingTimes shifts the completion time of every unbonding delegation
+// and redelegation entry and the unbonding time of every unbonding validator.
+func shiftStakingTimes(g *genesis.Genesis, delta time.Duration) (int, error) {
+	stakingGenesis, err := g.Staking()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i, val := range stakingGenesis.Validators {
+		if val.Status == stakingtypes.Unbonding {
+			stakingGenesis.Validators[i].UnbondingTime = val.UnbondingTime.Add(delta)
+			n++
+		}
+	}
+	for i, ubd := range stakingGenesis.UnbondingDelegations {
+		for j, entry := range ubd.Entries {
+			stakingGenesis.UnbondingDelegations[i].Entries[j].CompletionTime = entry.CompletionTime.Add(delta)
+			n++
+		}
+	}
+	for i, red := range stakingGenesis.Redelegations {
+		for j, entry := range red.Entries {
+			stakingGenesis.Redelegations[i].Entries[j].CompletionTime = entry.CompletionTime.Add(delta)
+			n++
+		}
+	}
+
+	return n, g.SetStaking(stakingGenesis)
+}
+
+// shiftVestingTimes shifts the start and end times of every vesting account.
+// Vesting times are in seconds, so delta is truncated to the second.
+func shiftVestingTimes(g *genesis.Genesis, delta time.Duration) (int, error) {
+	accounts, err := g.Accounts()
+	if err != nil {
+		return 0, err
+	}
+
+	seconds := int64(delta / time.Second)
+	n := 0
+	for _, acc := range accounts {
+		switch acc := acc.(type) {
+		case *authvesting.ContinuousVestingAccount:
+			acc.StartTime += seconds
+			acc.EndTime += seconds
+		case *authvesting.PeriodicVestingAccount:
+			acc.StartTime += seconds
+			acc.EndTime += seconds
+		case *authvesting.DelayedVestingAccount:
+			acc.EndTime += seconds
+		default:
+			continue
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	return n, g.SetAccounts(accounts)
+}
+
+// shiftGovTimes shifts the deposit end time of the proposals in deposit period
+// and the voting end time of the proposals in voting period. The times of
+// finished proposals are left as is.
+func shiftGovTimes(g *genesis.Genesis, delta time.Duration) (int, error) {
+	govGenesis, err := g.Gov()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i, proposal := range govGenesis.Proposals {
+		switch proposal.Status {
+		case govtypes.StatusDepositPeriod:
+			govGenesis.Proposals[i].DepositEndTime = proposal.DepositEndTime.Add(delta)
+		case govtypes.StatusVotingPeriod:
+			govGenesis.Proposals[i].VotingEndTime = proposal.VotingEndTime.Add(delta)
+		default:
+			continue
+		}
+		n++
+	}
+
+	return n, g.SetGov(govGenesis)
+}
+
+// shiftSlashingTimes shifts the jailed until time of every jailed validator.
+// Validators that were never jailed and tombstoned validators are left as is.
+func shiftSlashingTimes(g *genesis.Genesis, delta time.Duration) (int, error) {
+	slashingGenesis, err := g.Slashing()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i, info := range slashingGenesis.SigningInfos {
+		jailedUntil := info.ValidatorSigningInfo.JailedUntil
+		if info.ValidatorSigningInfo.Tombstoned || !jailedUntil.After(time.Unix(0, 0)) {
+			continue
+		}
+		slashingGenesis.SigningInfos[i].ValidatorSigningInfo.JailedUntil = jailedUntil.Add(delta)
+		n++
+	}
+
+	return n, g.SetSlashing(slashingGenesis)
+}
+
+// ensureGenesisAccount adds a base account for addr unless it already exists.
+func ensureGenesisAccount(g *genesis.Genesis, addr sdk.AccAddress) error {
+	accounts, err := g.Accounts()
+	if err != nil {
+		return err
+	}
+	if accounts.Contains(addr) {
+		return nil
+	}
+
+	accounts = append(accounts, authtypes.NewBaseAccount(addr, nil, 0, 0))
+	if err := g.SetAccounts(authtypes.SanitizeGenesisAccounts(accounts)); err != nil {
+		return err
+	}
+	g.Logf("created account %s", addr)
+	return nil
+}
+
+// addValidatorRewards adds the records of a validator without rewards whose
+// delegations all start from period 0, which is referenced by the validator
+// and each of its delegations.
+func addValidatorRewards(distrGenesis *distrtypes.GenesisState, operator string, delegations uint32) {
+	distrGenesis.ValidatorHistoricalRewards = append(distrGenesis.ValidatorHistoricalRewards, distrtypes.ValidatorHistoricalRewardsRecord{
+		ValidatorAddress: operator,
+		Period:           0,
+		Rewards:          distrtypes.NewValidatorHistoricalRewards(sdk.DecCoins{}, 1+delegations),
+	})
+	distrGenesis.ValidatorCurrentRewards = append(distrGenesis.ValidatorCurrentRewards, distrtypes.ValidatorCurrentRewardsRecord{
+		ValidatorAddress: operator,
+		Rewards:          distrtypes.NewValidatorCurrentRewards(sdk.DecCoins{}, 1),
+	})
+	distrGenesis.ValidatorAccumulatedCommissions = append(distrGenesis.ValidatorAccumulatedCommissions, distrtypes.ValidatorAccumulatedCommissionRecord{
+		ValidatorAddress: operator,
+		Accumulated:      distrtypes.InitialValidatorAccumulatedCommission(),
+	})
+	distrGenesis.OutstandingRewards = append(distrGenesis.OutstandingRewards, distrtypes.ValidatorOutstandingRewardsRecord{
+		ValidatorAddress:   operator,
+		OutstandingRewards: sdk.DecCoins{},
+	})
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cobra"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/go-btsg/genutils/app"
+	"github.com/go-btsg/genutils/genesis"
+)
+
+const (
+	flagInitChain      = "init-chain"
+	flagSkipInvariants = "skip-invariants"
+)
+
+// initChainValidator is a validator of the set returned by InitChain.
+type initChainValidator struct {
+	Operator string
+	Moniker  string
+	ConsAddr string
+	Power    int64
+}
+
+// VerifyGenesisCmd returns verify-genesis cobra Command.
+func VerifyGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-genesis [genesis-file]",
+		Short: "Verify that a genesis file is valid and can start a chain",
+		Long: `Verify that a genesis file is valid by running the stateless validation of
+every module.
+
+With --init-chain the genesis is additionally loaded into an in-memory
+application with the same modules as the chain, backed by an in-memory
+database, and InitChain is run on it. The resulting validator set is printed,
+and any panic raised while initializing the modules is reported. The crisis
+invariants are asserted at genesis unless --skip-invariants is given.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx := client.GetClientContextFromCmd(cmd)
+
+			g, err := genesis.Load(clientCtx.Codec, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := g.Doc.ValidateAndComplete(); err != nil {
+				return fmt.Errorf("invalid genesis doc: %w", err)
+			}
+			if err := app.ModuleBasics.ValidateGenesis(clientCtx.Codec, clientCtx.TxConfig, g.AppState); err != nil {
+				return fmt.Errorf("invalid app state: %w", err)
+			}
+
+			initChain, err := cmd.Flags().GetBool(flagInitChain)
+			if err != nil {
+				return err
+			}
+			if !initChain {
+				cmd.Printf("genesis file %s is valid\n", args[0])
+				return nil
+			}
+
+			skipInvariants, err := cmd.Flags().GetBool(flagSkipInvariants)
+			if err != nil {
+				return err
+			}
+
+			doc, err := g.GenesisDoc()
+			if err != nil {
+				return err
+			}
+
+			validators, err := dryRunInitChain(doc, skipInvariants)
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("InitChain succeeded with %d validators:\n", len(validators))
+			for _, val := range validators {
+				cmd.Printf("  %s %s power=%d moniker=%q\n", val.Operator, val.ConsAddr, val.Power, val.Moniker)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool(flagInitChain, false, "Run InitChain on an in-memory application")
+	cmd.Flags().Bool(flagSkipInvariants, false, "Do not assert the crisis invariants during InitChain")
+
+	return cmd
+}
+
+// dryRunInitChain runs InitChain with doc on an application backed by an
+// in-memory database and returns the resulting validator set. Like
+// Tendermint, it falls back to the validators of doc when InitChain returns
+// none. Panics raised by the modules are returned as errors.
+func dryRunInitChain(doc *tmtypes.GenesisDoc, skipInvariants bool) (validators []initChainValidator, err error) {
+	homeDir, err := ioutil.TempDir("", "verify-genesis")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(homeDir)
+
+	chainApp, err := app.NewApp(log.NewNopLogger(), dbm.NewMemDB(), homeDir, skipInvariants, app.MakeEncodingConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the application: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("InitChain panicked: %v", r)
+		}
+	}()
+
+	docValidators := tmtypes.TM2PB.ValidatorUpdates(tmtypes.NewValidatorSet(genesisValidators(doc)))
+	res := chainApp.InitChain(abci.RequestInitChain{
+		Time:            doc.GenesisTime,
+		ChainId:         doc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(doc.ConsensusParams),
+		Validators:      docValidators,
+		AppStateBytes:   doc.AppState,
+		InitialHeight:   doc.InitialHeight,
+	})
+
+	updates := res.Validators
+	if len(updates) == 0 {
+		updates = docValidators
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("InitChain returned an empty validator set and the genesis doc has no validators, the chain cannot start")
+	}
+
+	ctx := chainApp.BaseApp.NewContext(false, tmproto.Header{ChainID: doc.ChainID, Time: doc.GenesisTime})
+	for _, update := range updates {
+		pubKey, err := cryptocodec.FromTmProtoPublicKey(update.PubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		consAddr := sdk.ConsAddress(pubKey.Address())
+		val := initChainValidator{ConsAddr: consAddr.String(), Power: update.Power}
+		if stakingVal, found := chainApp.StakingKeeper.GetValidatorByConsAddr(ctx, consAddr); found {
+			val.Operator = stakingVal.OperatorAddress
+			val.Moniker = stakingVal.Description.Moniker
+		}
+		validators = append(validators, val)
+	}
+
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Power > validators[j].Power
+	})
+
+	return validators, nil
+}
+
+// genesisValidators converts the validators of doc to Tendermint validators.
+func genesisValidators(doc *tmtypes.GenesisDoc) []*tmtypes.Validator {
+	validators := make([]*tmtypes.Validator, len(doc.Validators))
+	for i, val := range doc.Validators {
+		validators[i] = tmtypes.NewValidator(val.PubKey, val.Power)
+	}
+	return validators
+}
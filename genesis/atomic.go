@@ -0,0 +1,104 @@
+package genesis
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to the path of a genesis file to name its backup.
+const BackupSuffix = ".bak"
+
+// WriteFileAtomic writes the file at path with write, compressing it according
+// to the extension of path. The content is written to a temporary file of the
+// same directory which is synced and then renamed to path, so that path is
+// either left untouched or fully written. With backup, the previous file at
+// path is kept at path.bak.
+func WriteFileAtomic(path string, backup bool, write func(io.Writer) error) (err error) {
+	mode := os.FileMode(0644)
+	info, statErr := os.Stat(path)
+	switch {
+	case statErr == nil:
+		mode = info.Mode().Perm()
+	case !os.IsNotExist(statErr):
+		return statErr
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w, err := compressWriter(tmp, path)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if backup && statErr == nil {
+		if err := backupFile(path); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// backupFile links path to path.bak, replacing any previous backup. The file
+// is copied if it cannot be linked.
+func backupFile(path string) error {
+	backupPath := path + BackupSuffix
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, backupPath); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return WriteFileAtomic(backupPath, false, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// syncDir flushes the directory entry of a renamed file. Errors are ignored
+// since some platforms cannot sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,116 @@
+package genesis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// readCloser is an io.ReadCloser made of a reader and its close function.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
+
+// writeCloser is an io.WriteCloser made of a writer and its close function.
+type writeCloser struct {
+	io.Writer
+	close func() error
+}
+
+func (w writeCloser) Close() error {
+	return w.close()
+}
+
+// OpenFile opens the genesis file at path for reading. Files compressed
+// with gzip or zstd are detected by their magic bytes and decompressed on the
+// fly.
+func OpenFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(f, bufferSize)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{Reader: zr, close: func() error {
+			zr.Close()
+			return f.Close()
+		}}, nil
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{Reader: zr, close: func() error {
+			zr.Close()
+			return f.Close()
+		}}, nil
+
+	default:
+		return readCloser{Reader: br, close: f.Close}, nil
+	}
+}
+
+// compressWriter wraps w into a writer compressing with gzip or zstd if path
+// ends with .gz or .zst. Closing the returned writer flushes it into w but does
+// not close w.
+func compressWriter(w io.Writer, path string) (io.WriteCloser, error) {
+	bw := bufio.NewWriterSize(w, bufferSize)
+
+	flush := func(zw io.Closer) func() error {
+		return func() error {
+			if zw != nil {
+				if err := zw.Close(); err != nil {
+					return err
+				}
+			}
+			return bw.Flush()
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		zw := gzip.NewWriter(bw)
+		return writeCloser{Writer: zw, close: flush(zw)}, nil
+
+	case ".zst":
+		zw, err := zstd.NewWriter(bw)
+		if err != nil {
+			return nil, err
+		}
+		return writeCloser{Writer: zw, close: flush(zw)}, nil
+
+	default:
+		return writeCloser{Writer: bw, close: flush(nil)}, nil
+	}
+}
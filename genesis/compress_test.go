@@ -0,0 +1,68 @@
+package genesis
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	g := testGenesis()
+	var plain bytes.Buffer
+	require.NoError(t, g.Encode(&plain))
+
+	tests := []struct {
+		name string
+		file string
+		// renameTo is the name the file is moved to before being read, if set
+		renameTo  string
+		wantMagic []byte
+	}{
+		{"plain", "genesis.json", "", []byte("{")},
+		{"gzip", "genesis.json.gz", "", gzipMagic},
+		{"zstd", "genesis.json.zst", "", zstdMagic},
+		{"upper case extension", "genesis.json.GZ", "", gzipMagic},
+		{"detected from the content", "genesis.json.gz", "genesis.json", gzipMagic},
+		{"zstd detected from the content", "genesis.json.zst", "genesis", zstdMagic},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tc.file)
+			require.NoError(t, g.Save(path, false))
+			if tc.renameTo != "" {
+				newPath := filepath.Join(dir, tc.renameTo)
+				require.NoError(t, os.Rename(path, newPath))
+				path = newPath
+			}
+
+			raw, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.True(t, bytes.HasPrefix(raw, tc.wantMagic), "file starts with %x", raw[:4])
+
+			f, err := OpenFile(path)
+			require.NoError(t, err)
+			defer f.Close()
+			got, err := ioutil.ReadAll(f)
+			require.NoError(t, err)
+			require.Equal(t, plain.String(), string(got))
+		})
+	}
+}
+
+func TestOpenFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	f, err := OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	got, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
@@ -0,0 +1,132 @@
+// Package genesis loads, edits and saves Cosmos SDK genesis files.
+//
+// A Genesis holds the Tendermint genesis doc and the app state of every module
+// as raw JSON. Module states are decoded on demand with the typed accessors,
+// such as Bank and SetBank, so that modules which are not edited are written
+// back untouched.
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Genesis is a genesis file whose app state is kept per module.
+type Genesis struct {
+	// Doc is the genesis doc. Its AppState is not maintained; the app state
+	// lives in AppState.
+	Doc tmtypes.GenesisDoc
+	// AppState is the JSON state of every module, by module name.
+	AppState map[string]json.RawMessage
+	// Log receives a line for every change made by a transform; nil discards
+	// them.
+	Log io.Writer
+
+	cdc       codec.Codec
+	logIndent string
+}
+
+// New returns a Genesis made of doc and appState, whose module states are
+// decoded with cdc.
+func New(cdc codec.Codec, doc tmtypes.GenesisDoc, appState map[string]json.RawMessage) *Genesis {
+	if appState == nil {
+		appState = make(map[string]json.RawMessage)
+	}
+	doc.AppState = nil
+	return &Genesis{Doc: doc, AppState: appState, cdc: cdc}
+}
+
+// Load reads the genesis file at path, which may be compressed with gzip or
+// zstd.
+func Load(cdc codec.Codec, path string) (*Genesis, error) {
+	appState := make(map[string]json.RawMessage)
+	doc, err := Stream(path, Visitor{
+		OnModule: func(module string, state json.RawMessage) error {
+			appState[module] = state
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return New(cdc, doc, appState), nil
+}
+
+// Clone returns a copy of g that is not affected by later edits of g.
+func (g *Genesis) Clone() *Genesis {
+	clone := *g
+	clone.Doc.Validators = append([]tmtypes.GenesisValidator(nil), g.Doc.Validators...)
+	if g.Doc.ConsensusParams != nil {
+		params := *g.Doc.ConsensusParams
+		clone.Doc.ConsensusParams = &params
+	}
+
+	// module states are replaced by SetModule, never edited in place
+	clone.AppState = make(map[string]json.RawMessage, len(g.AppState))
+	for module, state := range g.AppState {
+		clone.AppState[module] = state
+	}
+	return &clone
+}
+
+// Codec returns the codec used to decode the module states.
+func (g *Genesis) Codec() codec.Codec {
+	return g.cdc
+}
+
+// Logf writes a line to the log of g.
+func (g *Genesis) Logf(format string, args ...interface{}) {
+	if g.Log == nil {
+		return
+	}
+	fmt.Fprintf(g.Log, g.logIndent+format+"\n", args...)
+}
+
+// Save atomically writes g to path, compressed according to the extension of
+// path. With backup, the previous file at path is kept with the BackupSuffix.
+func (g *Genesis) Save(path string, backup bool) error {
+	if err := WriteFileAtomic(path, backup, g.Encode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Encode writes g as JSON to w, one module at a time.
+func (g *Genesis) Encode(w io.Writer) error {
+	return encode(w, g.Doc, g.AppState)
+}
+
+// GenesisDoc returns the genesis doc with its app state set, as expected by
+// Tendermint.
+func (g *Genesis) GenesisDoc() (*tmtypes.GenesisDoc, error) {
+	appState, err := json.Marshal(g.AppState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal application genesis state: %w", err)
+	}
+
+	doc := g.Doc
+	doc.AppState = appState
+	return &doc, nil
+}
+
+// UnmarshalModule decodes the state of module into state.
+func (g *Genesis) UnmarshalModule(module string, state codec.ProtoMarshaler) error {
+	if err := g.cdc.UnmarshalJSON(g.AppState[module], state); err != nil {
+		return fmt.Errorf("failed to unmarshal %s genesis state: %w", module, err)
+	}
+	return nil
+}
+
+// SetModule replaces the state of module with state.
+func (g *Genesis) SetModule(module string, state codec.ProtoMarshaler) error {
+	bz, err := g.cdc.MarshalJSON(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s genesis state: %w", module, err)
+	}
+	g.AppState[module] = bz
+	return nil
+}
@@ -0,0 +1,126 @@
+package genesis
+
+import (
+	"fmt"
+
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// Auth returns the auth genesis state.
+func (g *Genesis) Auth() (authtypes.GenesisState, error) {
+	var state authtypes.GenesisState
+	err := g.UnmarshalModule(authtypes.ModuleName, &state)
+	return state, err
+}
+
+// SetAuth replaces the auth genesis state.
+func (g *Genesis) SetAuth(state authtypes.GenesisState) error {
+	return g.SetModule(authtypes.ModuleName, &state)
+}
+
+// Accounts returns the unpacked accounts of the auth genesis state.
+func (g *Genesis) Accounts() (authtypes.GenesisAccounts, error) {
+	state, err := g.Auth()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := authtypes.UnpackAccounts(state.Accounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts from any: %w", err)
+	}
+	return accounts, nil
+}
+
+// SetAccounts replaces the accounts of the auth genesis state, keeping its
+// params.
+func (g *Genesis) SetAccounts(accounts authtypes.GenesisAccounts) error {
+	state, err := g.Auth()
+	if err != nil {
+		return err
+	}
+
+	state.Accounts, err = authtypes.PackAccounts(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to convert accounts into any's: %w", err)
+	}
+	return g.SetAuth(state)
+}
+
+// Bank returns the bank genesis state.
+func (g *Genesis) Bank() (banktypes.GenesisState, error) {
+	var state banktypes.GenesisState
+	err := g.UnmarshalModule(banktypes.ModuleName, &state)
+	return state, err
+}
+
+// SetBank replaces the bank genesis state.
+func (g *Genesis) SetBank(state banktypes.GenesisState) error {
+	return g.SetModule(banktypes.ModuleName, &state)
+}
+
+// Staking returns the staking genesis state.
+func (g *Genesis) Staking() (stakingtypes.GenesisState, error) {
+	var state stakingtypes.GenesisState
+	err := g.UnmarshalModule(stakingtypes.ModuleName, &state)
+	return state, err
+}
+
+// SetStaking replaces the staking genesis state.
+func (g *Genesis) SetStaking(state stakingtypes.GenesisState) error {
+	return g.SetModule(stakingtypes.ModuleName, &state)
+}
+
+// Distribution returns the distribution genesis state.
+func (g *Genesis) Distribution() (distrtypes.GenesisState, error) {
+	var state distrtypes.GenesisState
+	err := g.UnmarshalModule(distrtypes.ModuleName, &state)
+	return state, err
+}
+
+// SetDistribution replaces the distribution genesis state.
+func (g *Genesis) SetDistribution(state distrtypes.GenesisState) error {
+	return g.SetModule(distrtypes.ModuleName, &state)
+}
+
+// Gov returns the gov genesis state.
+func (g *Genesis) Gov() (govtypes.GenesisState, error) {
+	var state govtypes.GenesisState
+	err := g.UnmarshalModule(govtypes.ModuleName, &state)
+	return state, err
+}
+
+// SetGov replaces the gov genesis state.
+func (g *Genesis) SetGov(state govtypes.GenesisState) error {
+	return g.SetModule(govtypes.ModuleName, &state)
+}
+
+// Slashing returns the slashing genesis state.
+func (g *Genesis) Slashing() (slashingtypes.GenesisState, error) {
+	var state slashingtypes.GenesisState
+	err := g.UnmarshalModule(slashingtypes.ModuleName, &state)
+	return state, err
+}
+
+// SetSlashing replaces the slashing genesis state.
+func (g *Genesis) SetSlashing(state slashingtypes.GenesisState) error {
+	return g.SetModule(slashingtypes.ModuleName, &state)
+}
+
+// Mint returns the mint genesis state.
+func (g *Genesis) Mint() (minttypes.GenesisState, error) {
+	var state minttypes.GenesisState
+	err := g.UnmarshalModule(minttypes.ModuleName, &state)
+	return state, err
+}
+
+// SetMint replaces the mint genesis state.
+func (g *Genesis) SetMint(state minttypes.GenesisState) error {
+	return g.SetModule(minttypes.ModuleName, &state)
+}
@@ -0,0 +1,246 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const bufferSize = 1 << 20
+
+// Visitor selects what Stream materializes from a genesis file.
+type Visitor struct {
+	// Modules restricts the modules passed to OnModule; nil selects every
+	// module. Unselected modules are skipped without being buffered.
+	Modules map[string]bool
+	// OnModule receives the state of each selected module.
+	OnModule func(module string, state json.RawMessage) error
+	// Arrays maps a module and one of its top level array fields, such as
+	// bank and balances, to a callback receiving the array elements one by
+	// one. Such arrays are left out of the state given to OnModule.
+	Arrays map[string]map[string]func(json.RawMessage) error
+}
+
+// Stream reads the genesis file at path, which may be compressed, in a
+// single pass, feeding its app state to v, and returns the genesis doc without
+// its app state. Only one module section, or one array element for the arrays
+// of v, is held in memory at a time.
+func Stream(path string, v Visitor) (tmtypes.GenesisDoc, error) {
+	var doc tmtypes.GenesisDoc
+
+	f, err := OpenFile(path)
+	if err != nil {
+		return doc, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	header := make(map[string]json.RawMessage)
+	err = readObject(dec, func(key string) error {
+		if key != "app_state" {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			header[key] = raw
+			return nil
+		}
+
+		return readObject(dec, func(module string) error {
+			return v.visitModule(dec, module)
+		})
+	})
+	if err != nil {
+		return doc, fmt.Errorf("failed to decode genesis file %s: %w", path, err)
+	}
+
+	headerBz, err := json.Marshal(header)
+	if err != nil {
+		return doc, err
+	}
+	if err := tmjson.Unmarshal(headerBz, &doc); err != nil {
+		return doc, fmt.Errorf("failed to decode genesis file %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func (v Visitor) visitModule(dec *json.Decoder, module string) error {
+	selected := v.OnModule != nil && (v.Modules == nil || v.Modules[module])
+	arrays := v.Arrays[module]
+
+	if len(arrays) == 0 {
+		if !selected {
+			return skipValue(dec)
+		}
+
+		var state json.RawMessage
+		if err := dec.Decode(&state); err != nil {
+			return fmt.Errorf("%s: %w", module, err)
+		}
+		return v.OnModule(module, state)
+	}
+
+	fields := make(map[string]json.RawMessage)
+	err := readObject(dec, func(field string) error {
+		if visit, ok := arrays[field]; ok {
+			return readArray(dec, visit)
+		}
+		if !selected {
+			return skipValue(dec)
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		fields[field] = raw
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", module, err)
+	}
+	if !selected {
+		return nil
+	}
+
+	state, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return v.OnModule(module, state)
+}
+
+// readObject reads a JSON object from dec, calling fn for every key with the
+// decoder positioned on the value, which fn must consume. A null value is
+// treated as an empty object.
+func readObject(dec *json.Decoder, fn func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, got %v", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// readArray reads a JSON array from dec, passing its elements to fn. A null
+// value is treated as an empty array.
+func readArray(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next JSON value of dec without buffering it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// encode writes doc with genState as app state to w, one module at a
+// time and in module order, so that the whole document is never built in
+// memory.
+func encode(w io.Writer, doc tmtypes.GenesisDoc, genState map[string]json.RawMessage) error {
+	doc.AppState = nil
+	header, err := tmjson.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+
+	// reopen the header object to append the app state
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"app_state":{`); err != nil {
+		return err
+	}
+
+	modules := make([]string, 0, len(genState))
+	for module := range genState {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	for i, module := range modules {
+		key, err := json.Marshal(module)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			key = append([]byte{','}, key...)
+		}
+		if _, err := w.Write(append(key, ':')); err != nil {
+			return err
+		}
+
+		state := genState[module]
+		if len(state) == 0 {
+			state = json.RawMessage("null")
+		}
+		if _, err := w.Write(state); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "}}\n")
+	return err
+}
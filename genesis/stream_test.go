@@ -0,0 +1,169 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func testGenesis() *Genesis {
+	doc := tmtypes.GenesisDoc{
+		GenesisTime:     time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		ChainID:         "test-1",
+		InitialHeight:   42,
+		ConsensusParams: tmtypes.DefaultConsensusParams(),
+	}
+	return New(nil, doc, map[string]json.RawMessage{
+		"auth": json.RawMessage(`{"params":{"max_memo_characters":"256"},"accounts":[]}`),
+		"bank": json.RawMessage(`{"params":{"default_send_enabled":true},"balances":[{"address":"a","coins":[]},{"address":"b","coins":[{"denom":"ubtsg","amount":"1"}]}],"supply":[]}`),
+		"mint": json.RawMessage(`{"minter":{"inflation":"0.1"}}`),
+		"none": json.RawMessage(`null`),
+		"nums": json.RawMessage(`{"big":123456789012345678901234567890,"list":[1,2.5,"x",null,{"nested":[]}]}`),
+	})
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	g := testGenesis()
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, g.Save(path, false))
+
+	tests := []struct {
+		name string
+		// visitor builds the visitor writing into modules and elements
+		visitor    func(modules map[string]json.RawMessage, elements *[]json.RawMessage) Visitor
+		wantStates map[string]string
+		// wantElements are the bank balances passed to the array callback
+		wantElements []string
+	}{
+		{
+			name: "every module",
+			visitor: func(modules map[string]json.RawMessage, _ *[]json.RawMessage) Visitor {
+				return Visitor{OnModule: func(module string, state json.RawMessage) error {
+					modules[module] = state
+					return nil
+				}}
+			},
+			wantStates: map[string]string{
+				"auth": string(g.AppState["auth"]),
+				"bank": string(g.AppState["bank"]),
+				"mint": string(g.AppState["mint"]),
+				"none": "null",
+				"nums": string(g.AppState["nums"]),
+			},
+		},
+		{
+			name: "selected modules",
+			visitor: func(modules map[string]json.RawMessage, _ *[]json.RawMessage) Visitor {
+				return Visitor{
+					Modules: map[string]bool{"mint": true, "nums": true},
+					OnModule: func(module string, state json.RawMessage) error {
+						modules[module] = state
+						return nil
+					},
+				}
+			},
+			wantStates: map[string]string{
+				"mint": string(g.AppState["mint"]),
+				"nums": string(g.AppState["nums"]),
+			},
+		},
+		{
+			name: "streamed array",
+			visitor: func(modules map[string]json.RawMessage, elements *[]json.RawMessage) Visitor {
+				return Visitor{
+					Modules: map[string]bool{"bank": true},
+					OnModule: func(module string, state json.RawMessage) error {
+						modules[module] = state
+						return nil
+					},
+					Arrays: map[string]map[string]func(json.RawMessage) error{
+						"bank": {"balances": func(element json.RawMessage) error {
+							*elements = append(*elements, element)
+							return nil
+						}},
+					},
+				}
+			},
+			wantStates: map[string]string{
+				"bank": `{"params":{"default_send_enabled":true},"supply":[]}`,
+			},
+			wantElements: []string{
+				`{"address":"a","coins":[]}`,
+				`{"address":"b","coins":[{"denom":"ubtsg","amount":"1"}]}`,
+			},
+		},
+		{
+			name: "streamed array of unselected module",
+			visitor: func(_ map[string]json.RawMessage, elements *[]json.RawMessage) Visitor {
+				return Visitor{
+					Arrays: map[string]map[string]func(json.RawMessage) error{
+						"bank": {"balances": func(element json.RawMessage) error {
+							*elements = append(*elements, element)
+							return nil
+						}},
+					},
+				}
+			},
+			wantStates: map[string]string{},
+			wantElements: []string{
+				`{"address":"a","coins":[]}`,
+				`{"address":"b","coins":[{"denom":"ubtsg","amount":"1"}]}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			modules := make(map[string]json.RawMessage)
+			var elements []json.RawMessage
+
+			doc, err := Stream(path, tc.visitor(modules, &elements))
+			require.NoError(t, err)
+
+			require.Equal(t, g.Doc.ChainID, doc.ChainID)
+			require.True(t, g.Doc.GenesisTime.Equal(doc.GenesisTime))
+			require.Equal(t, g.Doc.InitialHeight, doc.InitialHeight)
+			require.Equal(t, g.Doc.ConsensusParams, doc.ConsensusParams)
+			require.Empty(t, doc.AppState)
+
+			require.Len(t, modules, len(tc.wantStates))
+			for module, want := range tc.wantStates {
+				require.JSONEq(t, want, string(modules[module]), module)
+			}
+
+			require.Len(t, elements, len(tc.wantElements))
+			for i, want := range tc.wantElements {
+				require.JSONEq(t, want, string(elements[i]))
+			}
+		})
+	}
+}
+
+func TestLoadEncodeRoundTrip(t *testing.T) {
+	g := testGenesis()
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, g.Save(path, false))
+
+	loaded, err := Load(nil, path)
+	require.NoError(t, err)
+
+	var want, got bytes.Buffer
+	require.NoError(t, g.Encode(&want))
+	require.NoError(t, loaded.Encode(&got))
+	// module states are written back byte for byte
+	require.Equal(t, want.String(), got.String())
+
+	// the encoded document is the genesis doc expected by Tendermint
+	doc, err := tmtypes.GenesisDocFromJSON(got.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "test-1", doc.ChainID)
+
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(doc.AppState, &appState))
+	require.Len(t, appState, len(g.AppState))
+}
@@ -0,0 +1,31 @@
+package genesis
+
+import "fmt"
+
+// Transform is a named edit of a genesis, such as setting its chain id or
+// funding an account.
+type Transform interface {
+	// Name identifies the kind of transform, e.g. set-chain-id.
+	Name() string
+	// Describe summarizes what Apply does with the parameters of the
+	// transform.
+	Describe() string
+	// Apply edits g and logs every change with g.Logf.
+	Apply(g *Genesis) error
+}
+
+// Apply runs transforms on g in order and stops at the first failure. Each
+// transform is logged with its description, followed by the changes it made.
+func (g *Genesis) Apply(transforms ...Transform) error {
+	for i, t := range transforms {
+		g.Logf("%d. %s: %s", i+1, t.Name(), t.Describe())
+
+		g.logIndent = "   "
+		err := t.Apply(g)
+		g.logIndent = ""
+		if err != nil {
+			return fmt.Errorf("transform %d (%s) failed: %w", i+1, t.Name(), err)
+		}
+	}
+	return nil
+}